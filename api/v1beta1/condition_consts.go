@@ -0,0 +1,147 @@
+package v1beta1
+
+import (
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// Conditions and condition Reasons for the VirtinkCluster and VirtinkMachine objects.
+
+const (
+	// InfraClusterReachableCondition documents whether the infra cluster, either the management cluster itself or
+	// the external cluster referenced by IdentityRef or InfraClusterSecretRef, can be reached.
+	InfraClusterReachableCondition capiv1beta1.ConditionType = "InfraClusterReachable"
+
+	// InfraClusterClientFailedReason (Severity=Error) documents a failure in building a client for the infra cluster.
+	InfraClusterClientFailedReason = "InfraClusterClientFailed"
+
+	// InfraClusterIdentityForbiddenReason (Severity=Error) documents infra cluster credentials that are not allowed
+	// to be used from the namespace of the VirtinkCluster, i.e. a VirtinkClusterIdentity that does not allow the
+	// namespace, or an InfraClusterSecretRef to a Secret in another namespace.
+	InfraClusterIdentityForbiddenReason = "InfraClusterIdentityForbidden"
+
+	// InfraClusterChangedReason (Severity=Error) documents a VirtinkMachine whose failure domain or VirtinkCluster
+	// resolves to another infra cluster or namespace than the one its VM and DataVolumes are created in.
+	InfraClusterChangedReason = "InfraClusterChanged"
+)
+
+const (
+	// FailureDomainsReachableCondition documents whether the infra clusters of the failure domains declared with an
+	// IdentityRef can be reached. Failure domains whose infra cluster can't be reached are not published.
+	FailureDomainsReachableCondition capiv1beta1.ConditionType = "FailureDomainsReachable"
+
+	// FailureDomainInfraClusterUnreachableReason (Severity=Warning) documents failure domains whose infra cluster
+	// can't be reached, or whose VirtinkClusterIdentity is not allowed to be used from the namespace of the
+	// VirtinkCluster.
+	FailureDomainInfraClusterUnreachableReason = "FailureDomainInfraClusterUnreachable"
+)
+
+const (
+	// ControlPlaneServiceReadyCondition documents the status of the Service that fronts the control plane nodes.
+	ControlPlaneServiceReadyCondition capiv1beta1.ConditionType = "ControlPlaneServiceReady"
+
+	// WaitingForOwnerClusterReason (Severity=Info) documents a VirtinkCluster waiting for its owner Cluster to be set.
+	WaitingForOwnerClusterReason = "WaitingForOwnerCluster"
+
+	// ControlPlaneServiceProvisioningFailedReason (Severity=Warning) documents a failure in creating or getting the
+	// control plane Service.
+	ControlPlaneServiceProvisioningFailedReason = "ControlPlaneServiceProvisioningFailed"
+
+	// WaitingForLoadBalancerReason (Severity=Info) documents a control plane Service of type LoadBalancer waiting
+	// for its ingress address to be assigned.
+	WaitingForLoadBalancerReason = "WaitingForLoadBalancer"
+
+	// WaitingForNodePortReason (Severity=Info) documents a control plane Service of type NodePort waiting for its
+	// node port to be allocated, or for a reachable host to publish the node port with.
+	WaitingForNodePortReason = "WaitingForNodePort"
+)
+
+const (
+	// ControlPlaneEndpointReadyCondition documents whether the ControlPlaneEndpoint provided by the user is set, when
+	// the ControlPlaneEndpointMode is External.
+	ControlPlaneEndpointReadyCondition capiv1beta1.ConditionType = "ControlPlaneEndpointReady"
+
+	// InvalidControlPlaneEndpointReason (Severity=Error) documents an externally managed ControlPlaneEndpoint that
+	// misses the host or the port.
+	InvalidControlPlaneEndpointReason = "InvalidControlPlaneEndpoint"
+)
+
+const (
+	// ControlPlaneServiceSyncedCondition documents whether the live control plane Service matches the
+	// ControlPlaneServiceTemplate.
+	ControlPlaneServiceSyncedCondition capiv1beta1.ConditionType = "ControlPlaneServiceSynced"
+
+	// ControlPlaneServiceDriftedReason (Severity=Warning) documents a live control plane Service that diverges from
+	// the ControlPlaneServiceTemplate and could not be brought back in line with it.
+	ControlPlaneServiceDriftedReason = "ControlPlaneServiceDrifted"
+)
+
+const (
+	// BootstrapDataReadyCondition documents whether the cluster infrastructure and the bootstrap data the
+	// VirtinkMachine depends on are available.
+	BootstrapDataReadyCondition capiv1beta1.ConditionType = "BootstrapDataReady"
+
+	// WaitingForOwnerMachineReason (Severity=Info) documents a VirtinkMachine waiting for its owner Machine to be set.
+	WaitingForOwnerMachineReason = "WaitingForOwnerMachine"
+
+	// WaitingForClusterInfrastructureReason (Severity=Info) documents a VirtinkMachine waiting for the cluster
+	// infrastructure to be ready.
+	WaitingForClusterInfrastructureReason = "WaitingForClusterInfrastructure"
+
+	// WaitingForBootstrapDataReason (Severity=Info) documents a VirtinkMachine waiting for the bootstrap data
+	// secret of its owner Machine to be set.
+	WaitingForBootstrapDataReason = "WaitingForBootstrapData"
+
+	// BootstrapDataSecretSyncFailedReason (Severity=Warning) documents a VirtinkMachine failed to sync the bootstrap
+	// data secret of its owner Machine to the infra cluster.
+	BootstrapDataSecretSyncFailedReason = "BootstrapDataSecretSyncFailed"
+
+	// UnsupportedBootstrapDataFormatReason (Severity=Error) documents a VirtinkMachine whose bootstrap data is in a
+	// format other than cloud-config.
+	UnsupportedBootstrapDataFormatReason = "UnsupportedBootstrapDataFormat"
+)
+
+const (
+	// IPAddressAllocatedCondition documents the allocation of the IP address from the IP pool, i.e. the IPPoolRef of
+	// a VirtinkMachine or the ControlPlaneEndpointIPPoolRef of a VirtinkCluster.
+	IPAddressAllocatedCondition capiv1beta1.ConditionType = "IPAddressAllocated"
+
+	// IPClaimProvisioningFailedReason (Severity=Warning) documents a failure in creating or getting the IPClaim.
+	IPClaimProvisioningFailedReason = "IPClaimProvisioningFailed"
+
+	// IPAddressAllocationFailedReason (Severity=Error) documents a failure reported by the IPAM provider when
+	// allocating the IP address.
+	IPAddressAllocationFailedReason = "IPAddressAllocationFailed"
+
+	// WaitingForIPAddressReason (Severity=Info) documents a VirtinkMachine or VirtinkCluster waiting for the IP
+	// address to be allocated.
+	WaitingForIPAddressReason = "WaitingForIPAddress"
+)
+
+const (
+	// VolumesReadyCondition documents the status of the DataVolumes created from the VolumeTemplates.
+	VolumesReadyCondition capiv1beta1.ConditionType = "VolumesReady"
+
+	// DataVolumeProvisioningFailedReason (Severity=Warning) documents a failure in creating or getting a DataVolume.
+	DataVolumeProvisioningFailedReason = "DataVolumeProvisioningFailed"
+
+	// WaitingForDataVolumeImportReason (Severity=Info) documents a VirtinkMachine waiting for the DataVolumes to be
+	// imported.
+	WaitingForDataVolumeImportReason = "WaitingForDataVolumeImport"
+
+	// DataVolumeImportFailedReason (Severity=Error) documents a DataVolume that failed to be imported.
+	DataVolumeImportFailedReason = "DataVolumeImportFailed"
+)
+
+const (
+	// VMProvisionedCondition documents the status of the provisioning of the Virtink VM.
+	VMProvisionedCondition capiv1beta1.ConditionType = "VMProvisioned"
+
+	// VMProvisioningFailedReason (Severity=Warning) documents a failure in creating or getting the VM.
+	VMProvisioningFailedReason = "VMProvisioningFailed"
+
+	// WaitingForVMSchedulingReason (Severity=Info) documents a VM waiting to be scheduled and started.
+	WaitingForVMSchedulingReason = "WaitingForVMScheduling"
+
+	// VMTerminatedReason (Severity=Error) documents a VM that has reached its final state and will not be restarted.
+	VMTerminatedReason = "VMTerminated"
+)
@@ -17,17 +17,79 @@ type VirtinkClusterSpec struct {
 	// ControlPlaneEndpoint represents the endpoint used to communicate with the control plane.
 	ControlPlaneEndpoint capiv1beta1.APIEndpoint `json:"controlPlaneEndpoint,omitempty"`
 
+	// ControlPlaneEndpointIPPoolRef is a reference to an IP pool to allocate the host of the ControlPlaneEndpoint
+	// from. The allocated address is set as the loadBalancerIP of the control plane service, which must be of type
+	// LoadBalancer, or is expected to be announced in the control plane nodes, e.g. by kube-vip, when the
+	// ControlPlaneEndpointMode is External. This field is optional.
+	ControlPlaneEndpointIPPoolRef *corev1.TypedLocalObjectReference `json:"controlPlaneEndpointIPPoolRef,omitempty"`
+
+	// ControlPlaneEndpointMode determines how the ControlPlaneEndpoint is managed. With Service, a service fronting
+	// the control plane nodes is created in the infra cluster and its address is published as the
+	// ControlPlaneEndpoint. With External, the ControlPlaneEndpoint is provided by the user, e.g. a VIP managed by
+	// kube-vip in the control plane nodes or a hardware load balancer, and no service is created. This field is
+	// optional, defaults to External if the ControlPlaneEndpoint is set on creation, otherwise Service.
+	// +kubebuilder:validation:Enum=Service;External
+	ControlPlaneEndpointMode ControlPlaneEndpointMode `json:"controlPlaneEndpointMode,omitempty"`
+
 	// ControlPlaneServiceTemplate can be used to modify service that fronts the control plane nodes to handle the
-	// api-server traffic (port 6443). This field is optional, by default control plane nodes will use a service
-	// of type ClusterIP, which will make workload cluster only accessible within the same cluster. Note, this does
-	// not aim to expose the entire service spec to users, but only provides capability to modify the service metadata
-	// and the service type.
+	// api-server traffic (port 6443 by default). This field is optional, by default control plane nodes will use a
+	// service of type ClusterIP, which will make workload cluster only accessible within the same cluster. Note, this
+	// does not aim to expose the entire service spec to users, but only provides capability to modify the service
+	// metadata and the service type and ports.
 	ControlPlaneServiceTemplate ControlPlaneServiceTemplate `json:"controlPlaneServiceTemplate,omitempty"`
 
-	// InfraClusterSecretRef is a reference to a secret with a kubeconfig for external cluster used for infra.
+	// InfraClusterSecretRef is a reference to a secret with a kubeconfig for external cluster used for infra. The
+	// secret must be in the namespace of the VirtinkCluster.
+	// Deprecated: use IdentityRef instead.
 	InfraClusterSecretRef *corev1.ObjectReference `json:"infraClusterSecretRef,omitempty"`
+
+	// IdentityRef is a reference to a VirtinkClusterIdentity with a kubeconfig for external cluster used for infra.
+	// It takes precedence over InfraClusterSecretRef.
+	IdentityRef *VirtinkClusterIdentityReference `json:"identityRef,omitempty"`
+
+	// FailureDomains is a list of failure domains of the infra cluster, e.g. zones or racks of the infra cluster
+	// nodes. Each failure domain is the value of the FailureDomainTopologyKey label of the infra cluster nodes it
+	// contains. This field is optional.
+	FailureDomains []FailureDomain `json:"failureDomains,omitempty"`
+
+	// FailureDomainTopologyKey is the label key of the infra cluster nodes whose value is the failure domain the
+	// nodes belong to. The VMs of machines in a failure domain are required to be scheduled to the nodes with the
+	// label. If FailureDomains is empty, the failure domains are discovered from the values of the label on the
+	// infra cluster nodes. This field is optional, defaults to topology.kubernetes.io/zone if FailureDomains is set.
+	FailureDomainTopologyKey string `json:"failureDomainTopologyKey,omitempty"`
+}
+
+// FailureDomain describes a failure domain of the infra cluster.
+type FailureDomain struct {
+	// Name is the value of the FailureDomainTopologyKey label of the infra cluster nodes in the failure domain.
+	Name string `json:"name"`
+
+	// ControlPlane determines if the failure domain is suitable for use by control plane machines.
+	ControlPlane bool `json:"controlPlane,omitempty"`
+
+	// IdentityRef is a reference to a VirtinkClusterIdentity of the infra cluster the VMs of machines in the failure
+	// domain are created in, in which case the VMs are not required to be scheduled to the nodes with the
+	// FailureDomainTopologyKey label. This field is optional, by default the infra cluster of the VirtinkCluster is
+	// used. Note that the control plane service is always created in the infra cluster of the VirtinkCluster, and can
+	// only front the control plane nodes in it.
+	IdentityRef *VirtinkClusterIdentityReference `json:"identityRef,omitempty"`
+
+	// Namespace is the namespace in the infra cluster the VMs and DataVolumes of machines in the failure domain are
+	// created in. This field is optional, by default the namespace of the VirtualMachineTemplate is used.
+	Namespace string `json:"namespace,omitempty"`
 }
 
+// ControlPlaneEndpointMode describes how the ControlPlaneEndpoint is managed.
+type ControlPlaneEndpointMode string
+
+const (
+	// ControlPlaneEndpointModeService publishes the address of the control plane service as the ControlPlaneEndpoint.
+	ControlPlaneEndpointModeService ControlPlaneEndpointMode = "Service"
+
+	// ControlPlaneEndpointModeExternal uses the ControlPlaneEndpoint provided by the user.
+	ControlPlaneEndpointModeExternal ControlPlaneEndpointMode = "External"
+)
+
 // ControlPlaneServiceTemplate describes the template for the control plane service.
 type ControlPlaneServiceTemplate struct {
 	// Service metadata allows to set labels and annotations for the service.
@@ -39,6 +101,37 @@ type ControlPlaneServiceTemplate struct {
 	// api-server traffic (port 6443). This field is optional, by default control plane nodes will use a service
 	// of type ClusterIP, which will make workload cluster only accessible within the same cluster.
 	Type *corev1.ServiceType `json:"type,omitempty"`
+
+	// Port is the port exposed by the service, which is also used as the port of the ControlPlaneEndpoint.
+	// This field is optional, defaults to 6443.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port *int32 `json:"port,omitempty"`
+
+	// TargetPort is the port the api-server listens on in the control plane nodes. This field is optional,
+	// defaults to 6443.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	TargetPort *int32 `json:"targetPort,omitempty"`
+
+	// NodePortPublishing configures how the ControlPlaneEndpoint is published when the service is of type NodePort.
+	// This field is optional, by default the ClusterIP of the service is used as the ControlPlaneEndpoint, which is
+	// only reachable within the infra cluster.
+	NodePortPublishing *NodePortPublishing `json:"nodePortPublishing,omitempty"`
+}
+
+// NodePortPublishing describes how to publish the ControlPlaneEndpoint of a control plane service of type NodePort,
+// with a reachable host and the allocated node port.
+type NodePortPublishing struct {
+	// Hosts is a list of hosts that forward the node port to the service, e.g. a VIP or the addresses of selected
+	// infra cluster nodes. This field is optional, by default the addresses of the ready infra cluster nodes are used.
+	// The host of the ControlPlaneEndpoint is kept as long as it remains in the list.
+	Hosts []string `json:"hosts,omitempty"`
+
+	// AddressType is the type of the infra cluster node addresses used when Hosts is empty. This field is optional,
+	// defaults to InternalIP.
+	// +kubebuilder:validation:Enum=InternalIP;ExternalIP
+	AddressType corev1.NodeAddressType `json:"addressType,omitempty"`
 }
 
 // VirtinkClusterStatus defines the observed state of VirtinkCluster
@@ -47,6 +140,12 @@ type VirtinkClusterStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	Ready bool `json:"ready,omitempty"`
+
+	// Conditions defines current service state of the VirtinkCluster.
+	Conditions capiv1beta1.Conditions `json:"conditions,omitempty"`
+
+	// FailureDomains is a list of failure domain objects synced from the infra provider.
+	FailureDomains capiv1beta1.FailureDomains `json:"failureDomains,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -64,6 +163,16 @@ type VirtinkCluster struct {
 	Status VirtinkClusterStatus `json:"status,omitempty"`
 }
 
+// GetConditions returns the set of conditions for this object.
+func (c *VirtinkCluster) GetConditions() capiv1beta1.Conditions {
+	return c.Status.Conditions
+}
+
+// SetConditions sets the conditions on this object.
+func (c *VirtinkCluster) SetConditions(conditions capiv1beta1.Conditions) {
+	c.Status.Conditions = conditions
+}
+
 //+kubebuilder:object:root=true
 
 // VirtinkClusterList contains a list of VirtinkCluster
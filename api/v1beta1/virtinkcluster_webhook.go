@@ -0,0 +1,106 @@
+package v1beta1
+
+import (
+	"fmt"
+
+	ipamv1 "github.com/metal3-io/ip-address-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *VirtinkCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-virtinkcluster,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=virtinkclusters,verbs=create;update,versions=v1beta1,name=default.virtinkcluster.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &VirtinkCluster{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *VirtinkCluster) Default() {
+	defaultVirtinkClusterSpec(&r.Spec, r.Namespace)
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-virtinkcluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=virtinkclusters,verbs=create;update,versions=v1beta1,name=validation.virtinkcluster.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &VirtinkCluster{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkCluster) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkCluster) ValidateUpdate(old runtime.Object) error {
+	oldCluster, ok := old.(*VirtinkCluster)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a VirtinkCluster but got a %T", old))
+	}
+
+	// Only the fields made invalid by the update are rejected, so that VirtinkClusters created before the validation
+	// was in place can still be updated, e.g. to remove their finalizer.
+	allErrs := newFieldErrors(validateVirtinkClusterSpec(&r.Spec, field.NewPath("spec")), validateVirtinkClusterSpec(&oldCluster.Spec, field.NewPath("spec")))
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("VirtinkCluster").GroupKind(), r.Name, allErrs)
+	}
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkCluster) ValidateDelete() error {
+	return nil
+}
+
+func (r *VirtinkCluster) validate() error {
+	allErrs := validateVirtinkClusterSpec(&r.Spec, field.NewPath("spec"))
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("VirtinkCluster").GroupKind(), r.Name, allErrs)
+	}
+	return nil
+}
+
+// defaultVirtinkClusterSpec defaults the type of the control plane service to ClusterIP, and its namespace to the
+// namespace of the VirtinkCluster, which is where the service is created in the infra cluster if not set.
+func defaultVirtinkClusterSpec(spec *VirtinkClusterSpec, namespace string) {
+	if spec.ControlPlaneServiceTemplate.Type == nil {
+		serviceType := corev1.ServiceTypeClusterIP
+		spec.ControlPlaneServiceTemplate.Type = &serviceType
+	}
+	if spec.ControlPlaneServiceTemplate.ObjectMeta.Namespace == "" {
+		spec.ControlPlaneServiceTemplate.ObjectMeta.Namespace = namespace
+	}
+}
+
+func validateVirtinkClusterSpec(spec *VirtinkClusterSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if serviceType := spec.ControlPlaneServiceTemplate.Type; serviceType != nil {
+		supportedServiceTypes := []string{string(corev1.ServiceTypeClusterIP), string(corev1.ServiceTypeNodePort), string(corev1.ServiceTypeLoadBalancer)}
+		switch *serviceType {
+		case corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("controlPlaneServiceTemplate", "type"), *serviceType, supportedServiceTypes))
+		}
+	}
+
+	allErrs = append(allErrs, validateIPPoolRef(spec.ControlPlaneEndpointIPPoolRef, fldPath.Child("controlPlaneEndpointIPPoolRef"), ipamv1.GroupVersion.Group)...)
+
+	failureDomains := map[string]bool{}
+	for i, failureDomain := range spec.FailureDomains {
+		namePath := fldPath.Child("failureDomains").Index(i).Child("name")
+		switch {
+		case failureDomain.Name == "":
+			allErrs = append(allErrs, field.Required(namePath, ""))
+		case failureDomains[failureDomain.Name]:
+			allErrs = append(allErrs, field.Duplicate(namePath, failureDomain.Name))
+		}
+		failureDomains[failureDomain.Name] = true
+	}
+	return allErrs
+}
@@ -0,0 +1,33 @@
+package v1beta1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVirtinkClusterValidateUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	// The VirtinkCluster was created before the validation was in place.
+	oldCluster := &VirtinkCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "cluster",
+			Namespace:  "default",
+			Finalizers: []string{"capch.cluster.x-k8s.io"},
+		},
+		Spec: VirtinkClusterSpec{
+			FailureDomains: []FailureDomain{{Name: "zone-a"}, {Name: "zone-a"}},
+		},
+	}
+
+	newCluster := oldCluster.DeepCopy()
+	newCluster.Finalizers = nil
+	g.Expect(newCluster.ValidateUpdate(oldCluster)).To(Succeed())
+
+	serviceType := corev1.ServiceTypeExternalName
+	newCluster.Spec.ControlPlaneServiceTemplate.Type = &serviceType
+	g.Expect(newCluster.ValidateUpdate(oldCluster)).To(MatchError(ContainSubstring("spec.controlPlaneServiceTemplate.type")))
+}
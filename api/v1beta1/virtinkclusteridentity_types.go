@@ -0,0 +1,57 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VirtinkClusterIdentitySpec defines the desired state of VirtinkClusterIdentity
+type VirtinkClusterIdentitySpec struct {
+	// SecretRef is a reference to a secret with a kubeconfig for external cluster used for infra.
+	SecretRef corev1.SecretReference `json:"secretRef"`
+
+	// AllowedNamespaces is used to identify the namespaces the VirtinkClusters are allowed to use the identity from.
+	// Namespaces can be selected either with a list of namespaces or with a label selector. An empty
+	// allowedNamespaces object allows VirtinkClusters in any namespace to use the identity, while a nil one allows
+	// none of them.
+	AllowedNamespaces *AllowedNamespaces `json:"allowedNamespaces,omitempty"`
+}
+
+// AllowedNamespaces selects the namespaces the VirtinkClusters are allowed to use an identity from.
+type AllowedNamespaces struct {
+	// NamespaceList is a list of namespaces the VirtinkClusters are allowed to use the identity from.
+	NamespaceList []string `json:"list,omitempty"`
+
+	// Selector is a label selector of the namespaces the VirtinkClusters are allowed to use the identity from.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// VirtinkClusterIdentityReference is a reference to a VirtinkClusterIdentity.
+type VirtinkClusterIdentityReference struct {
+	// Name of the VirtinkClusterIdentity.
+	Name string `json:"name"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// VirtinkClusterIdentity is the Schema for the virtinkclusteridentities API
+type VirtinkClusterIdentity struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VirtinkClusterIdentitySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VirtinkClusterIdentityList contains a list of VirtinkClusterIdentity
+type VirtinkClusterIdentityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VirtinkClusterIdentity `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VirtinkClusterIdentity{}, &VirtinkClusterIdentityList{})
+}
@@ -2,6 +2,7 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 // VirtinkClusterTemplateSpec defines the desired state of VirtinkClusterTemplate
@@ -10,7 +11,11 @@ type VirtinkClusterTemplateSpec struct {
 }
 
 type VirtinkClusterTemplateResource struct {
-	Spec VirtinkClusterSpec `json:"spec"`
+	// Standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	ObjectMeta capiv1beta1.ObjectMeta `json:"metadata,omitempty"`
+	Spec       VirtinkClusterSpec     `json:"spec"`
 }
 
 //+kubebuilder:object:root=true
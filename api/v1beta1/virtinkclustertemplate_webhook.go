@@ -0,0 +1,68 @@
+package v1beta1
+
+import (
+	"fmt"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *VirtinkClusterTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-virtinkclustertemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=virtinkclustertemplates,verbs=create;update,versions=v1beta1,name=default.virtinkclustertemplate.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &VirtinkClusterTemplate{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *VirtinkClusterTemplate) Default() {
+	defaultVirtinkClusterSpec(&r.Spec.Template.Spec, r.Namespace)
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-virtinkclustertemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=virtinkclustertemplates,verbs=create;update,versions=v1beta1,name=validation.virtinkclustertemplate.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &VirtinkClusterTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkClusterTemplate) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkClusterTemplate) ValidateUpdate(old runtime.Object) error {
+	oldTemplate, ok := old.(*VirtinkClusterTemplate)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a VirtinkClusterTemplate but got a %T", old))
+	}
+
+	// The old template may have been created before the defaulting webhook was in place, so it is defaulted before
+	// comparing to not reject updates of its metadata.
+	oldTemplate = oldTemplate.DeepCopy()
+	oldTemplate.Default()
+	if !apiequality.Semantic.DeepEqual(r.Spec, oldTemplate.Spec) {
+		return apierrors.NewInvalid(GroupVersion.WithKind("VirtinkClusterTemplate").GroupKind(), r.Name, field.ErrorList{
+			field.Forbidden(field.NewPath("spec"), "VirtinkClusterTemplate spec is immutable, create a new template instead"),
+		})
+	}
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkClusterTemplate) ValidateDelete() error {
+	return nil
+}
+
+func (r *VirtinkClusterTemplate) validate() error {
+	allErrs := validateVirtinkClusterSpec(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("VirtinkClusterTemplate").GroupKind(), r.Name, allErrs)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package v1beta1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVirtinkClusterTemplateValidateUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	oldTemplate := &VirtinkClusterTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "template", Namespace: "default"},
+	}
+
+	newTemplate := oldTemplate.DeepCopy()
+	newTemplate.Spec.Template.ObjectMeta.Labels = map[string]string{"updated": "true"}
+	newTemplate.Default()
+	g.Expect(newTemplate.ValidateUpdate(oldTemplate)).To(MatchError(ContainSubstring("immutable")))
+
+	newTemplate = oldTemplate.DeepCopy()
+	newTemplate.Labels = map[string]string{"updated": "true"}
+	newTemplate.Default()
+	g.Expect(newTemplate.ValidateUpdate(oldTemplate)).To(Succeed())
+}
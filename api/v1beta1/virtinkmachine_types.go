@@ -5,6 +5,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	capierrors "sigs.k8s.io/cluster-api/errors"
 )
 
@@ -21,6 +22,20 @@ type VirtinkMachineSpec struct {
 	VirtualMachineTemplate VirtualMachineTemplateSpec        `json:"virtualMachineTemplate"`
 	VolumeTemplates        []VolumeTemplateSource            `json:"volumeTemplates,omitempty"`
 	IPPoolRef              *corev1.TypedLocalObjectReference `json:"ipPoolRef,omitempty"`
+
+	// InterfaceIPPools binds interfaces of the VM to IP pools, an IP address is allocated from each of the IP pools
+	// for the interface, e.g. for VMs with multiple networks or dual-stack interfaces.
+	// +optional
+	InterfaceIPPools []InterfaceIPPool `json:"interfaceIPPools,omitempty"`
+}
+
+// InterfaceIPPool binds an interface of the VM to an IP pool.
+type InterfaceIPPool struct {
+	// InterfaceName is the name of the interface in the instance of the VirtualMachineTemplate.
+	InterfaceName string `json:"interfaceName"`
+
+	// IPPoolRef is a reference to the IP pool to allocate the IP address of the interface from.
+	IPPoolRef corev1.TypedLocalObjectReference `json:"ipPoolRef"`
 }
 
 type VirtualMachineTemplateSpec struct {
@@ -31,8 +46,24 @@ type VirtualMachineTemplateSpec struct {
 
 type VolumeTemplateSource struct {
 	DataVolume *VolumeTemplateSourceDataVolume `json:"dataVolume,omitempty"`
+
+	// ReclaimPolicy defines what happens to the DataVolume and its PVC when the VirtinkMachine is deleted.
+	// Defaults to Delete.
+	// +kubebuilder:validation:Enum=Delete;Retain
+	// +kubebuilder:default=Delete
+	ReclaimPolicy VolumeReclaimPolicy `json:"reclaimPolicy,omitempty"`
 }
 
+// VolumeReclaimPolicy describes a policy for the end-of-life maintenance of volumes created from VolumeTemplates.
+type VolumeReclaimPolicy string
+
+const (
+	// VolumeReclaimDelete means the volume will be deleted along with the VirtinkMachine.
+	VolumeReclaimDelete VolumeReclaimPolicy = "Delete"
+	// VolumeReclaimRetain means the volume will be left in the infra cluster for manual reclamation.
+	VolumeReclaimRetain VolumeReclaimPolicy = "Retain"
+)
+
 type VolumeTemplateSourceDataVolume struct {
 	// +kubebuilder:pruning:PreserveUnknownFields
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -47,6 +78,43 @@ type VirtinkMachineStatus struct {
 	Ready          bool                           `json:"ready,omitempty"`
 	FailureReason  *capierrors.MachineStatusError `json:"failureReason,omitempty"`
 	FailureMessage *string                        `json:"failureMessage,omitempty"`
+
+	// Addresses contains the associated addresses for the virtual machine.
+	Addresses []capiv1beta1.MachineAddress `json:"addresses,omitempty"`
+
+	// Conditions defines current service state of the VirtinkMachine.
+	Conditions capiv1beta1.Conditions `json:"conditions,omitempty"`
+
+	// FailureDomain is the failure domain the VM and DataVolumes of the VirtinkMachine are created in, which
+	// determines the infra cluster and namespace they are tracked and deleted in.
+	FailureDomain *string `json:"failureDomain,omitempty"`
+
+	// InfraClusterSecretRef is the reference to the infra cluster kubeconfig Secret the VM and DataVolumes of the
+	// VirtinkMachine are created with, which is resolved once from the failure domain or the VirtinkCluster. It is
+	// not set if the management cluster itself is used for infra.
+	// +optional
+	InfraClusterSecretRef *corev1.ObjectReference `json:"infraClusterSecretRef,omitempty"`
+
+	// InfraNamespace is the namespace of the infra cluster the VM and DataVolumes of the VirtinkMachine are created
+	// in, which is resolved once along with InfraClusterSecretRef.
+	// +optional
+	InfraNamespace string `json:"infraNamespace,omitempty"`
+
+	// MACAddresses are the MAC addresses allocated to the interfaces of the VM. They are kept for the lifetime of the
+	// VirtinkMachine and set on the interfaces of the VM.
+	// +optional
+	MACAddresses []InterfaceMACAddress `json:"macAddresses,omitempty"`
+}
+
+// InterfaceMACAddress is the MAC address allocated to an interface of the VM.
+type InterfaceMACAddress struct {
+	// InterfaceName is the name of the interface in the instance of the VirtualMachineTemplate, which is empty if
+	// the VM has no interfaces.
+	// +optional
+	InterfaceName string `json:"interfaceName,omitempty"`
+
+	// MACAddress is the MAC address of the interface.
+	MACAddress string `json:"macAddress"`
 }
 
 //+kubebuilder:object:root=true
@@ -63,6 +131,16 @@ type VirtinkMachine struct {
 	Status VirtinkMachineStatus `json:"status,omitempty"`
 }
 
+// GetConditions returns the set of conditions for this object.
+func (m *VirtinkMachine) GetConditions() capiv1beta1.Conditions {
+	return m.Status.Conditions
+}
+
+// SetConditions sets the conditions on this object.
+func (m *VirtinkMachine) SetConditions(conditions capiv1beta1.Conditions) {
+	m.Status.Conditions = conditions
+}
+
 //+kubebuilder:object:root=true
 
 // VirtinkMachineList contains a list of VirtinkMachine
@@ -0,0 +1,205 @@
+package v1beta1
+
+import (
+	"fmt"
+
+	ipamv1 "github.com/metal3-io/ip-address-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	capiipamv1alpha1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *VirtinkMachine) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-virtinkmachine,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=virtinkmachines,verbs=create;update,versions=v1beta1,name=default.virtinkmachine.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &VirtinkMachine{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *VirtinkMachine) Default() {
+	defaultVirtinkMachineSpec(&r.Spec, r.Namespace)
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-virtinkmachine,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=virtinkmachines,verbs=create;update,versions=v1beta1,name=validation.virtinkmachine.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &VirtinkMachine{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkMachine) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkMachine) ValidateUpdate(old runtime.Object) error {
+	oldMachine, ok := old.(*VirtinkMachine)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a VirtinkMachine but got a %T", old))
+	}
+
+	// Only the fields made invalid by the update are rejected, so that VirtinkMachines created before the validation
+	// was in place can still be updated, e.g. to remove their finalizer.
+	allErrs := newFieldErrors(validateVirtinkMachineSpec(&r.Spec, field.NewPath("spec")), validateVirtinkMachineSpec(&oldMachine.Spec, field.NewPath("spec")))
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("VirtinkMachine").GroupKind(), r.Name, allErrs)
+	}
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkMachine) ValidateDelete() error {
+	return nil
+}
+
+func (r *VirtinkMachine) validate() error {
+	allErrs := validateVirtinkMachineSpec(&r.Spec, field.NewPath("spec"))
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("VirtinkMachine").GroupKind(), r.Name, allErrs)
+	}
+	return nil
+}
+
+// defaultVirtinkMachineSpec defaults the namespace of the VirtualMachineTemplate to the namespace of the VirtinkMachine,
+// which is where the VM and DataVolumes are created in the infra cluster if not set.
+func defaultVirtinkMachineSpec(spec *VirtinkMachineSpec, namespace string) {
+	if spec.VirtualMachineTemplate.ObjectMeta.Namespace == "" {
+		spec.VirtualMachineTemplate.ObjectMeta.Namespace = namespace
+	}
+}
+
+func validateVirtinkMachineSpec(spec *VirtinkMachineSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	instancePath := fldPath.Child("virtualMachineTemplate", "spec", "instance")
+	instance := spec.VirtualMachineTemplate.Spec.Instance
+	if instance.CPU.Sockets == 0 {
+		allErrs = append(allErrs, field.Required(instancePath.Child("cpu", "sockets"), "must be greater than 0"))
+	}
+	if instance.CPU.CoresPerSocket == 0 {
+		allErrs = append(allErrs, field.Required(instancePath.Child("cpu", "coresPerSocket"), "must be greater than 0"))
+	}
+	if instance.Memory.Size == nil || instance.Memory.Size.Sign() <= 0 {
+		allErrs = append(allErrs, field.Required(instancePath.Child("memory", "size"), "must be greater than 0"))
+	}
+
+	dataVolumeTemplates := map[string]bool{}
+	for i, volume := range spec.VolumeTemplates {
+		if volume.DataVolume == nil {
+			continue
+		}
+		namePath := fldPath.Child("volumeTemplates").Index(i).Child("dataVolume", "metadata", "name")
+		switch {
+		case volume.DataVolume.Name == "":
+			allErrs = append(allErrs, field.Required(namePath, ""))
+		case dataVolumeTemplates[volume.DataVolume.Name]:
+			allErrs = append(allErrs, field.Duplicate(namePath, volume.DataVolume.Name))
+		}
+		dataVolumeTemplates[volume.DataVolume.Name] = true
+	}
+
+	volumesPath := fldPath.Child("virtualMachineTemplate", "spec", "volumes")
+	for i, volume := range spec.VirtualMachineTemplate.Spec.Volumes {
+		if volume.DataVolume == nil {
+			continue
+		}
+		if !dataVolumeTemplates[volume.DataVolume.VolumeName] {
+			allErrs = append(allErrs, field.NotFound(volumesPath.Index(i).Child("dataVolume", "volumeName"), volume.DataVolume.VolumeName))
+		}
+	}
+
+	allErrs = append(allErrs, validateIPPoolRef(spec.IPPoolRef, fldPath.Child("ipPoolRef"), ipamv1.GroupVersion.Group, capiipamv1alpha1.GroupVersion.Group)...)
+	allErrs = append(allErrs, validateInterfaceIPPools(spec, fldPath.Child("interfaceIPPools"))...)
+	return allErrs
+}
+
+func validateInterfaceIPPools(spec *VirtinkMachineSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(spec.InterfaceIPPools) == 0 {
+		return allErrs
+	}
+	if spec.IPPoolRef != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "may not be set together with ipPoolRef"))
+	}
+
+	interfaces := map[string]bool{}
+	for _, iface := range spec.VirtualMachineTemplate.Spec.Instance.Interfaces {
+		interfaces[iface.Name] = true
+	}
+	// The IP claim of an interface IP pool is named after the interface and the IP pool.
+	interfaceIPPools := map[string]bool{}
+	for i, interfaceIPPool := range spec.InterfaceIPPools {
+		interfaceNamePath := fldPath.Index(i).Child("interfaceName")
+		switch {
+		case interfaceIPPool.InterfaceName == "":
+			allErrs = append(allErrs, field.Required(interfaceNamePath, ""))
+		case !interfaces[interfaceIPPool.InterfaceName]:
+			allErrs = append(allErrs, field.NotFound(interfaceNamePath, interfaceIPPool.InterfaceName))
+		}
+		allErrs = append(allErrs, validateIPPoolRef(&interfaceIPPool.IPPoolRef, fldPath.Index(i).Child("ipPoolRef"), ipamv1.GroupVersion.Group, capiipamv1alpha1.GroupVersion.Group)...)
+
+		key := interfaceIPPool.InterfaceName + "/" + interfaceIPPool.IPPoolRef.Name
+		if interfaceIPPools[key] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), interfaceIPPool))
+		}
+		interfaceIPPools[key] = true
+	}
+	return allErrs
+}
+
+// validateIPPoolRef validates a reference to an IP pool of one of the supported API groups. The kind of the IP pool
+// is only known in advance for the metal3 IPAM, since Cluster API IPAM providers define their own pool kinds.
+func validateIPPoolRef(ref *corev1.TypedLocalObjectReference, fldPath *field.Path, supportedAPIGroups ...string) field.ErrorList {
+	var allErrs field.ErrorList
+	if ref == nil {
+		return allErrs
+	}
+
+	// References without an API group predate support for other API groups and refer to metal3 IPPools.
+	apiGroup := ipamv1.GroupVersion.Group
+	if ref.APIGroup != nil {
+		apiGroup = *ref.APIGroup
+	}
+	supported := false
+	for _, supportedAPIGroup := range supportedAPIGroups {
+		if apiGroup == supportedAPIGroup {
+			supported = true
+		}
+	}
+	if !supported {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("apiGroup"), apiGroup, supportedAPIGroups))
+	}
+
+	switch {
+	case apiGroup == ipamv1.GroupVersion.Group && ref.Kind != "IPPool":
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("kind"), ref.Kind, []string{"IPPool"}))
+	case ref.Kind == "":
+		allErrs = append(allErrs, field.Required(fldPath.Child("kind"), ""))
+	}
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), ""))
+	}
+	return allErrs
+}
+
+// newFieldErrors returns the errors of the fields which are valid in the old object.
+func newFieldErrors(errs field.ErrorList, oldErrs field.ErrorList) field.ErrorList {
+	invalidFields := map[string]bool{}
+	for _, err := range oldErrs {
+		invalidFields[err.Field] = true
+	}
+
+	var allErrs field.ErrorList
+	for _, err := range errs {
+		if !invalidFields[err.Field] {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return allErrs
+}
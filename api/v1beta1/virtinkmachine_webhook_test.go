@@ -0,0 +1,207 @@
+package v1beta1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	virtv1alpha1 "github.com/smartxworks/virtink/pkg/apis/virt/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func newVirtinkMachineSpec() VirtinkMachineSpec {
+	memorySize := resource.MustParse("1Gi")
+	return VirtinkMachineSpec{
+		VirtualMachineTemplate: VirtualMachineTemplateSpec{
+			Spec: virtv1alpha1.VirtualMachineSpec{
+				Instance: virtv1alpha1.Instance{
+					CPU:    virtv1alpha1.CPU{Sockets: 1, CoresPerSocket: 1},
+					Memory: virtv1alpha1.Memory{Size: &memorySize},
+				},
+				Volumes: []virtv1alpha1.Volume{{
+					Name: "rootfs",
+					VolumeSource: virtv1alpha1.VolumeSource{
+						DataVolume: &virtv1alpha1.DataVolumeVolumeSource{VolumeName: "rootfs"},
+					},
+				}},
+			},
+		},
+		VolumeTemplates: []VolumeTemplateSource{{
+			DataVolume: &VolumeTemplateSourceDataVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "rootfs"},
+			},
+		}},
+		IPPoolRef: &corev1.TypedLocalObjectReference{
+			APIGroup: pointer.String("ipam.metal3.io"),
+			Kind:     "IPPool",
+			Name:     "pool",
+		},
+	}
+}
+
+func TestVirtinkMachineValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(spec *VirtinkMachineSpec)
+		wantErr string
+	}{{
+		name:   "valid",
+		mutate: func(spec *VirtinkMachineSpec) {},
+	}, {
+		name: "missing volume template",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.VolumeTemplates = nil
+		},
+		wantErr: "spec.virtualMachineTemplate.spec.volumes[0].dataVolume.volumeName",
+	}, {
+		name: "empty CPU",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.VirtualMachineTemplate.Spec.Instance.CPU = virtv1alpha1.CPU{}
+		},
+		wantErr: "spec.virtualMachineTemplate.spec.instance.cpu.sockets",
+	}, {
+		name: "empty memory",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.VirtualMachineTemplate.Spec.Instance.Memory.Size = nil
+		},
+		wantErr: "spec.virtualMachineTemplate.spec.instance.memory.size",
+	}, {
+		name: "unsupported IP pool kind",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.IPPoolRef.Kind = "InClusterIPPool"
+		},
+		wantErr: "spec.ipPoolRef.kind",
+	}, {
+		name: "Cluster API IP pool",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.IPPoolRef.APIGroup = pointer.String("ipam.cluster.x-k8s.io")
+			spec.IPPoolRef.Kind = "InClusterIPPool"
+		},
+	}, {
+		name: "unsupported IP pool API group",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.IPPoolRef.APIGroup = pointer.String("example.com")
+		},
+		wantErr: "spec.ipPoolRef.apiGroup",
+	}, {
+		name: "IP pool without API group",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.IPPoolRef.APIGroup = nil
+		},
+	}, {
+		name: "unsupported IP pool kind without API group",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.IPPoolRef.APIGroup = nil
+			spec.IPPoolRef.Kind = "InClusterIPPool"
+		},
+		wantErr: "spec.ipPoolRef.kind",
+	}, {
+		name: "interface IP pools",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.VirtualMachineTemplate.Spec.Instance.Interfaces = []virtv1alpha1.Interface{{Name: "pod"}, {Name: "storage"}}
+			spec.InterfaceIPPools = []InterfaceIPPool{{
+				InterfaceName: "pod",
+				IPPoolRef:     *spec.IPPoolRef,
+			}, {
+				InterfaceName: "storage",
+				IPPoolRef:     *spec.IPPoolRef,
+			}}
+			spec.IPPoolRef = nil
+		},
+	}, {
+		name: "interface IP pools with IP pool",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.VirtualMachineTemplate.Spec.Instance.Interfaces = []virtv1alpha1.Interface{{Name: "pod"}}
+			spec.InterfaceIPPools = []InterfaceIPPool{{
+				InterfaceName: "pod",
+				IPPoolRef:     *spec.IPPoolRef,
+			}}
+		},
+		wantErr: "spec.interfaceIPPools: Forbidden",
+	}, {
+		name: "interface IP pool with unknown interface",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.InterfaceIPPools = []InterfaceIPPool{{
+				InterfaceName: "storage",
+				IPPoolRef:     *spec.IPPoolRef,
+			}}
+			spec.IPPoolRef = nil
+		},
+		wantErr: "spec.interfaceIPPools[0].interfaceName",
+	}, {
+		name: "duplicate interface IP pools",
+		mutate: func(spec *VirtinkMachineSpec) {
+			spec.VirtualMachineTemplate.Spec.Instance.Interfaces = []virtv1alpha1.Interface{{Name: "pod"}}
+			spec.InterfaceIPPools = []InterfaceIPPool{{
+				InterfaceName: "pod",
+				IPPoolRef:     *spec.IPPoolRef,
+			}, {
+				InterfaceName: "pod",
+				IPPoolRef:     *spec.IPPoolRef,
+			}}
+			spec.IPPoolRef = nil
+		},
+		wantErr: "spec.interfaceIPPools[1]: Duplicate",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			machine := &VirtinkMachine{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "default"},
+				Spec:       newVirtinkMachineSpec(),
+			}
+			tt.mutate(&machine.Spec)
+			machine.Default()
+			if tt.wantErr == "" {
+				g.Expect(machine.ValidateCreate()).To(Succeed())
+			} else {
+				g.Expect(machine.ValidateCreate()).To(MatchError(ContainSubstring(tt.wantErr)))
+			}
+		})
+	}
+}
+
+func TestVirtinkMachineValidateUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	// The VirtinkMachine was created before the validation was in place.
+	oldMachine := &VirtinkMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "machine",
+			Namespace:  "default",
+			Finalizers: []string{"capch.cluster.x-k8s.io"},
+		},
+		Spec: newVirtinkMachineSpec(),
+	}
+	oldMachine.Spec.VirtualMachineTemplate.Spec.Instance.Memory.Size = nil
+	oldMachine.Spec.IPPoolRef.APIGroup = pointer.String("example.com")
+
+	newMachine := oldMachine.DeepCopy()
+	newMachine.Finalizers = nil
+	newMachine.Spec.ProviderID = pointer.String("virtink://uid")
+	g.Expect(newMachine.ValidateUpdate(oldMachine)).To(Succeed())
+
+	newMachine.Spec.VirtualMachineTemplate.Spec.Instance.CPU.Sockets = 0
+	g.Expect(newMachine.ValidateUpdate(oldMachine)).To(MatchError(ContainSubstring("spec.virtualMachineTemplate.spec.instance.cpu.sockets")))
+}
+
+func TestVirtinkMachineTemplateValidateUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	oldTemplate := &VirtinkMachineTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "template", Namespace: "default"},
+		Spec: VirtinkMachineTemplateSpec{
+			Template: VirtinkMachineTemplateSpecTemplate{Spec: newVirtinkMachineSpec()},
+		},
+	}
+
+	newTemplate := oldTemplate.DeepCopy()
+	newTemplate.Labels = map[string]string{"updated": "true"}
+	newTemplate.Default()
+	g.Expect(newTemplate.ValidateUpdate(oldTemplate)).To(Succeed())
+
+	newTemplate.Spec.Template.Spec.VirtualMachineTemplate.Spec.Instance.CPU.CoresPerSocket = 2
+	g.Expect(newTemplate.ValidateUpdate(oldTemplate)).To(MatchError(ContainSubstring("immutable")))
+}
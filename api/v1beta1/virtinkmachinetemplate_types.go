@@ -1,7 +1,9 @@
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -16,15 +18,35 @@ type VirtinkMachineTemplateSpec struct {
 }
 
 type VirtinkMachineTemplateSpecTemplate struct {
-	// +kubebuilder:pruning:PreserveUnknownFields
-	metav1.ObjectMeta `json:"metadata,omitempty"`
-	Spec              VirtinkMachineSpec `json:"spec,omitempty"`
+	// Standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	ObjectMeta capiv1beta1.ObjectMeta `json:"metadata,omitempty"`
+	Spec       VirtinkMachineSpec     `json:"spec,omitempty"`
 }
 
 // VirtinkMachineTemplateStatus defines the observed state of VirtinkMachineTemplate
 type VirtinkMachineTemplateStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// Capacity is the resource capacity of the nodes created from the template, including cpu, memory,
+	// ephemeral-storage of the rootfs volume and pods. It is used by cluster-autoscaler to scale MachineDeployments up
+	// from zero.
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// NodeInfo describes the nodes created from the template.
+	NodeInfo *NodeInfo `json:"nodeInfo,omitempty"`
+}
+
+// NodeInfo describes the operating system and architecture of a node.
+type NodeInfo struct {
+	// Architecture is the CPU architecture of the node, e.g. amd64 or arm64. It is only known if the VMs are required
+	// to be scheduled to infra cluster nodes of the architecture.
+	Architecture string `json:"architecture,omitempty"`
+
+	// OperatingSystem is the operating system of the node.
+	OperatingSystem string `json:"operatingSystem,omitempty"`
 }
 
 //+kubebuilder:object:root=true
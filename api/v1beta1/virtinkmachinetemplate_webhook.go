@@ -0,0 +1,64 @@
+package v1beta1
+
+import (
+	"fmt"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *VirtinkMachineTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-virtinkmachinetemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=virtinkmachinetemplates,verbs=create;update,versions=v1beta1,name=default.virtinkmachinetemplate.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &VirtinkMachineTemplate{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *VirtinkMachineTemplate) Default() {
+	defaultVirtinkMachineSpec(&r.Spec.Template.Spec, r.Namespace)
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-virtinkmachinetemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=virtinkmachinetemplates,verbs=create;update,versions=v1beta1,name=validation.virtinkmachinetemplate.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &VirtinkMachineTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkMachineTemplate) ValidateCreate() error {
+	allErrs := validateVirtinkMachineSpec(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("VirtinkMachineTemplate").GroupKind(), r.Name, allErrs)
+	}
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkMachineTemplate) ValidateUpdate(old runtime.Object) error {
+	oldTemplate, ok := old.(*VirtinkMachineTemplate)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a VirtinkMachineTemplate but got a %T", old))
+	}
+
+	// The old template may have been created before the defaulting webhook was in place, so it is defaulted before
+	// comparing to not reject updates of its metadata.
+	oldTemplate = oldTemplate.DeepCopy()
+	oldTemplate.Default()
+	if !apiequality.Semantic.DeepEqual(r.Spec, oldTemplate.Spec) {
+		return apierrors.NewInvalid(GroupVersion.WithKind("VirtinkMachineTemplate").GroupKind(), r.Name, field.ErrorList{
+			field.Forbidden(field.NewPath("spec"), "VirtinkMachineTemplate spec is immutable, create a new template instead"),
+		})
+	}
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *VirtinkMachineTemplate) ValidateDelete() error {
+	return nil
+}
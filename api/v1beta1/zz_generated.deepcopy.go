@@ -7,10 +7,37 @@ package v1beta1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	apiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/errors"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllowedNamespaces) DeepCopyInto(out *AllowedNamespaces) {
+	*out = *in
+	if in.NamespaceList != nil {
+		in, out := &in.NamespaceList, &out.NamespaceList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllowedNamespaces.
+func (in *AllowedNamespaces) DeepCopy() *AllowedNamespaces {
+	if in == nil {
+		return nil
+	}
+	out := new(AllowedNamespaces)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ControlPlaneServiceTemplate) DeepCopyInto(out *ControlPlaneServiceTemplate) {
 	*out = *in
@@ -20,6 +47,21 @@ func (in *ControlPlaneServiceTemplate) DeepCopyInto(out *ControlPlaneServiceTemp
 		*out = new(v1.ServiceType)
 		**out = **in
 	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetPort != nil {
+		in, out := &in.TargetPort, &out.TargetPort
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodePortPublishing != nil {
+		in, out := &in.NodePortPublishing, &out.NodePortPublishing
+		*out = new(NodePortPublishing)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneServiceTemplate.
@@ -32,13 +74,99 @@ func (in *ControlPlaneServiceTemplate) DeepCopy() *ControlPlaneServiceTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureDomain) DeepCopyInto(out *FailureDomain) {
+	*out = *in
+	if in.IdentityRef != nil {
+		in, out := &in.IdentityRef, &out.IdentityRef
+		*out = new(VirtinkClusterIdentityReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureDomain.
+func (in *FailureDomain) DeepCopy() *FailureDomain {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureDomain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InterfaceIPPool) DeepCopyInto(out *InterfaceIPPool) {
+	*out = *in
+	in.IPPoolRef.DeepCopyInto(&out.IPPoolRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InterfaceIPPool.
+func (in *InterfaceIPPool) DeepCopy() *InterfaceIPPool {
+	if in == nil {
+		return nil
+	}
+	out := new(InterfaceIPPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InterfaceMACAddress) DeepCopyInto(out *InterfaceMACAddress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InterfaceMACAddress.
+func (in *InterfaceMACAddress) DeepCopy() *InterfaceMACAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(InterfaceMACAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeInfo) DeepCopyInto(out *NodeInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeInfo.
+func (in *NodeInfo) DeepCopy() *NodeInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodePortPublishing) DeepCopyInto(out *NodePortPublishing) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodePortPublishing.
+func (in *NodePortPublishing) DeepCopy() *NodePortPublishing {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePortPublishing)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtinkCluster) DeepCopyInto(out *VirtinkCluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkCluster.
@@ -59,6 +187,100 @@ func (in *VirtinkCluster) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtinkClusterIdentity) DeepCopyInto(out *VirtinkClusterIdentity) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkClusterIdentity.
+func (in *VirtinkClusterIdentity) DeepCopy() *VirtinkClusterIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtinkClusterIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtinkClusterIdentity) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtinkClusterIdentityList) DeepCopyInto(out *VirtinkClusterIdentityList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VirtinkClusterIdentity, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkClusterIdentityList.
+func (in *VirtinkClusterIdentityList) DeepCopy() *VirtinkClusterIdentityList {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtinkClusterIdentityList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtinkClusterIdentityList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtinkClusterIdentityReference) DeepCopyInto(out *VirtinkClusterIdentityReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkClusterIdentityReference.
+func (in *VirtinkClusterIdentityReference) DeepCopy() *VirtinkClusterIdentityReference {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtinkClusterIdentityReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtinkClusterIdentitySpec) DeepCopyInto(out *VirtinkClusterIdentitySpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(AllowedNamespaces)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkClusterIdentitySpec.
+func (in *VirtinkClusterIdentitySpec) DeepCopy() *VirtinkClusterIdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtinkClusterIdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtinkClusterList) DeepCopyInto(out *VirtinkClusterList) {
 	*out = *in
@@ -95,12 +317,29 @@ func (in *VirtinkClusterList) DeepCopyObject() runtime.Object {
 func (in *VirtinkClusterSpec) DeepCopyInto(out *VirtinkClusterSpec) {
 	*out = *in
 	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
+	if in.ControlPlaneEndpointIPPoolRef != nil {
+		in, out := &in.ControlPlaneEndpointIPPoolRef, &out.ControlPlaneEndpointIPPoolRef
+		*out = new(v1.TypedLocalObjectReference)
+		(*in).DeepCopyInto(*out)
+	}
 	in.ControlPlaneServiceTemplate.DeepCopyInto(&out.ControlPlaneServiceTemplate)
 	if in.InfraClusterSecretRef != nil {
 		in, out := &in.InfraClusterSecretRef, &out.InfraClusterSecretRef
 		*out = new(v1.ObjectReference)
 		**out = **in
 	}
+	if in.IdentityRef != nil {
+		in, out := &in.IdentityRef, &out.IdentityRef
+		*out = new(VirtinkClusterIdentityReference)
+		**out = **in
+	}
+	if in.FailureDomains != nil {
+		in, out := &in.FailureDomains, &out.FailureDomains
+		*out = make([]FailureDomain, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkClusterSpec.
@@ -116,6 +355,20 @@ func (in *VirtinkClusterSpec) DeepCopy() *VirtinkClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtinkClusterStatus) DeepCopyInto(out *VirtinkClusterStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailureDomains != nil {
+		in, out := &in.FailureDomains, &out.FailureDomains
+		*out = make(apiv1beta1.FailureDomains, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkClusterStatus.
@@ -189,6 +442,7 @@ func (in *VirtinkClusterTemplateList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtinkClusterTemplateResource) DeepCopyInto(out *VirtinkClusterTemplateResource) {
 	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 }
 
@@ -298,6 +552,13 @@ func (in *VirtinkMachineSpec) DeepCopyInto(out *VirtinkMachineSpec) {
 		*out = new(v1.TypedLocalObjectReference)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.InterfaceIPPools != nil {
+		in, out := &in.InterfaceIPPools, &out.InterfaceIPPools
+		*out = make([]InterfaceIPPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkMachineSpec.
@@ -323,6 +584,33 @@ func (in *VirtinkMachineStatus) DeepCopyInto(out *VirtinkMachineStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]apiv1beta1.MachineAddress, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailureDomain != nil {
+		in, out := &in.FailureDomain, &out.FailureDomain
+		*out = new(string)
+		**out = **in
+	}
+	if in.InfraClusterSecretRef != nil {
+		in, out := &in.InfraClusterSecretRef, &out.InfraClusterSecretRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.MACAddresses != nil {
+		in, out := &in.MACAddresses, &out.MACAddresses
+		*out = make([]InterfaceMACAddress, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkMachineStatus.
@@ -341,7 +629,7 @@ func (in *VirtinkMachineTemplate) DeepCopyInto(out *VirtinkMachineTemplate) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkMachineTemplate.
@@ -430,6 +718,18 @@ func (in *VirtinkMachineTemplateSpecTemplate) DeepCopy() *VirtinkMachineTemplate
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtinkMachineTemplateStatus) DeepCopyInto(out *VirtinkMachineTemplateStatus) {
 	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.NodeInfo != nil {
+		in, out := &in.NodeInfo, &out.NodeInfo
+		*out = new(NodeInfo)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtinkMachineTemplateStatus.
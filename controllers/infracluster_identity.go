@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrastructurev1beta1 "github.com/smartxworks/cluster-api-provider-virtink/api/v1beta1"
+)
+
+var errInfraClusterIdentityForbidden = errors.New("infra cluster credentials are not allowed to be used from the namespace")
+
+// getInfraClusterSecretRef returns the reference to the infra cluster kubeconfig Secret the VirtinkCluster is allowed
+// to use, or nil if the management cluster itself is used for infra. The Secret of a VirtinkClusterIdentity is only
+// returned if the namespace of the VirtinkCluster is allowed by the identity, and the deprecated InfraClusterSecretRef
+// may only reference a Secret in the namespace of the VirtinkCluster, unless deleting is set so that the objects created
+// with a Secret in another namespace before the restriction can still be cleaned up.
+func getInfraClusterSecretRef(ctx context.Context, c client.Client, cluster *infrastructurev1beta1.VirtinkCluster, deleting bool) (*corev1.ObjectReference, error) {
+	if cluster.Spec.IdentityRef != nil {
+		return getIdentitySecretRef(ctx, c, cluster.Spec.IdentityRef, cluster.Namespace)
+	}
+
+	if cluster.Spec.InfraClusterSecretRef != nil {
+		secretRef := cluster.Spec.InfraClusterSecretRef.DeepCopy()
+		if secretRef.Namespace == "" {
+			secretRef.Namespace = cluster.Namespace
+		}
+		if secretRef.Namespace != cluster.Namespace && !deleting {
+			return nil, fmt.Errorf("InfraClusterSecretRef in namespace %q: %w", secretRef.Namespace, errInfraClusterIdentityForbidden)
+		}
+		return secretRef, nil
+	}
+
+	return nil, nil
+}
+
+// getFailureDomainInfraClusterSecretRef returns the reference to the infra cluster kubeconfig Secret of the failure
+// domain, which falls back to the one of the VirtinkCluster if the failure domain has no IdentityRef.
+func getFailureDomainInfraClusterSecretRef(ctx context.Context, c client.Client, cluster *infrastructurev1beta1.VirtinkCluster, failureDomain *infrastructurev1beta1.FailureDomain, deleting bool) (*corev1.ObjectReference, error) {
+	if failureDomain != nil && failureDomain.IdentityRef != nil {
+		return getIdentitySecretRef(ctx, c, failureDomain.IdentityRef, cluster.Namespace)
+	}
+	return getInfraClusterSecretRef(ctx, c, cluster, deleting)
+}
+
+func getIdentitySecretRef(ctx context.Context, c client.Client, identityRef *infrastructurev1beta1.VirtinkClusterIdentityReference, namespace string) (*corev1.ObjectReference, error) {
+	var identity infrastructurev1beta1.VirtinkClusterIdentity
+	if err := c.Get(ctx, types.NamespacedName{Name: identityRef.Name}, &identity); err != nil {
+		return nil, fmt.Errorf("get VirtinkClusterIdentity: %s", err)
+	}
+
+	allowed, err := isNamespaceAllowed(ctx, c, identity.Spec.AllowedNamespaces, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, fmt.Errorf("VirtinkClusterIdentity %q: %w", identity.Name, errInfraClusterIdentityForbidden)
+	}
+
+	return &corev1.ObjectReference{
+		Kind:      "Secret",
+		Name:      identity.Spec.SecretRef.Name,
+		Namespace: identity.Spec.SecretRef.Namespace,
+	}, nil
+}
+
+// getFailureDomain returns the failure domain of the given name declared in the VirtinkCluster, or nil if it is not
+// declared.
+func getFailureDomain(cluster *infrastructurev1beta1.VirtinkCluster, name *string) *infrastructurev1beta1.FailureDomain {
+	if name == nil {
+		return nil
+	}
+	for i := range cluster.Spec.FailureDomains {
+		if cluster.Spec.FailureDomains[i].Name == *name {
+			return &cluster.Spec.FailureDomains[i]
+		}
+	}
+	return nil
+}
+
+func isNamespaceAllowed(ctx context.Context, c client.Client, allowedNamespaces *infrastructurev1beta1.AllowedNamespaces, namespace string) (bool, error) {
+	if allowedNamespaces == nil {
+		return false, nil
+	}
+	if len(allowedNamespaces.NamespaceList) == 0 && allowedNamespaces.Selector == nil {
+		return true, nil
+	}
+
+	for _, allowedNamespace := range allowedNamespaces.NamespaceList {
+		if allowedNamespace == namespace {
+			return true, nil
+		}
+	}
+
+	if allowedNamespaces.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(allowedNamespaces.Selector)
+		if err != nil {
+			return false, fmt.Errorf("parse allowed namespaces selector: %s", err)
+		}
+
+		var ns corev1.Namespace
+		if err := c.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+			return false, fmt.Errorf("get Namespace: %s", err)
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func markInfraClusterUnreachable(to conditions.Setter, err error) {
+	if errors.Is(err, errInfraClusterIdentityForbidden) {
+		conditions.MarkFalse(to, infrastructurev1beta1.InfraClusterReachableCondition, infrastructurev1beta1.InfraClusterIdentityForbiddenReason, capiv1beta1.ConditionSeverityError, err.Error())
+		return
+	}
+	conditions.MarkFalse(to, infrastructurev1beta1.InfraClusterReachableCondition, infrastructurev1beta1.InfraClusterClientFailedReason, capiv1beta1.ConditionSeverityError, err.Error())
+}
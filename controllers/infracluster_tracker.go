@@ -0,0 +1,613 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	infraClusterClientTimeout               = 10 * time.Second
+	infraClusterCacheSyncTimeout            = 30 * time.Second
+	infraClusterHealthCheckInterval         = 10 * time.Second
+	infraClusterHealthCheckTimeout          = 5 * time.Second
+	infraClusterHealthCheckFailureThreshold = 3
+
+	infraClusterKubeConfigKey = "kubeconfig"
+	infraClusterServerKey     = "server"
+)
+
+// InfraClusterTracker manages one cached client per infra cluster kubeconfig Secret, so that the reconcilers of all
+// VirtinkClusters and VirtinkMachines sharing an infra cluster read from the same informers instead of hitting the
+// infra cluster API server on every reconcile. The cache of a client only watches the infra namespaces its users
+// work in, so that namespace-scoped credentials suffice. The client of a Secret is dropped once all of its users are
+// released.
+type InfraClusterTracker struct {
+	client               client.Client
+	scheme               *runtime.Scheme
+	allowExecPlugins     bool
+	allowCredentialFiles bool
+
+	lock       sync.Mutex
+	accessors  map[types.NamespacedName]*infraClusterAccessor
+	users      map[types.NamespacedName]sets.String
+	namespaces map[types.NamespacedName]sets.String
+	watches    map[types.NamespacedName]map[string]*infraClusterWatch
+
+	// secretLocks serializes building the accessor of the same Secret, without blocking the accessors of other
+	// Secrets while an infra cluster is slow to respond.
+	secretLocks map[types.NamespacedName]*sync.Mutex
+}
+
+type infraClusterAccessor struct {
+	secretResourceVersion string
+	secretData            map[string][]byte
+	token                 *infraClusterToken
+	config                *rest.Config
+	namespaces            sets.String
+	cache                 cache.Cache
+	client                client.Client
+	ctx                   context.Context
+	stop                  context.CancelFunc
+}
+
+// Watcher is implemented by controllers that can start watching a source, e.g. controller.Controller.
+type Watcher interface {
+	Watch(src source.Source, eventhandler handler.EventHandler, predicates ...predicate.Predicate) error
+}
+
+// InfraClusterWatchInput specifies the parameters used to establish a watch on an infra cluster.
+type InfraClusterWatchInput struct {
+	// Name is used to dedupe watches of the same kind from the same controller on an infra cluster.
+	Name string
+
+	// Watcher is the controller that will receive the events.
+	Watcher Watcher
+
+	// Kind is the type of object to watch.
+	Kind client.Object
+
+	// EventHandler contains the event handlers to invoke for resource events.
+	EventHandler handler.EventHandler
+
+	// Predicates is used to filter resource events.
+	Predicates []predicate.Predicate
+}
+
+// NewInfraClusterTracker creates an InfraClusterTracker which reads the kubeconfig Secrets with the given client.
+// Kubeconfigs with exec credential plugins or auth providers, which would run commands or plugins inside the
+// controller, are rejected unless allowExecPlugins is set, and kubeconfigs referencing credential files, which would be
+// read from the file system of the controller, are rejected unless allowCredentialFiles is set.
+func NewInfraClusterTracker(c client.Client, scheme *runtime.Scheme, allowExecPlugins bool, allowCredentialFiles bool) *InfraClusterTracker {
+	return &InfraClusterTracker{
+		client:               c,
+		scheme:               scheme,
+		allowExecPlugins:     allowExecPlugins,
+		allowCredentialFiles: allowCredentialFiles,
+		accessors:            map[types.NamespacedName]*infraClusterAccessor{},
+		users:                map[types.NamespacedName]sets.String{},
+		namespaces:           map[types.NamespacedName]sets.String{},
+		watches:              map[types.NamespacedName]map[string]*infraClusterWatch{},
+		secretLocks:          map[types.NamespacedName]*sync.Mutex{},
+	}
+}
+
+// GetClient returns a cached client for the infra cluster referenced by the kubeconfig Secret, which reads the
+// namespaced objects of the given infra namespace, and records the user of the client until it is released by
+// ReleaseClients. The client is rebuilt once the resourceVersion of the Secret changes or a namespace not watched yet
+// is used, except when only the token of a Secret with structured credentials is rotated, in which case the new token
+// is used by the existing client.
+func (t *InfraClusterTracker) GetClient(ctx context.Context, user client.Object, infraClusterSecretRef *corev1.ObjectReference, namespace string) (client.Client, error) {
+	accessor, err := t.getAccessor(ctx, infraClusterSecretRef, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	secretKey := types.NamespacedName{Name: infraClusterSecretRef.Name, Namespace: infraClusterSecretRef.Namespace}
+	if _, ok := t.users[secretKey]; !ok {
+		t.users[secretKey] = sets.NewString()
+	}
+	t.users[secretKey].Insert(infraClusterUserKey(user))
+	return accessor.client, nil
+}
+
+// ReleaseClients releases the clients used by the user except the ones of the kept Secrets, e.g. all of them once the
+// user is deleted, or the former ones once the user switches to other Secrets. The clients and informers of the
+// Secrets without any other user are stopped.
+func (t *InfraClusterTracker) ReleaseClients(user client.Object, keep ...*corev1.ObjectReference) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	keptSecretKeys := map[types.NamespacedName]bool{}
+	for _, ref := range keep {
+		if ref != nil {
+			keptSecretKeys[types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}] = true
+		}
+	}
+
+	userKey := infraClusterUserKey(user)
+	for secretKey, users := range t.users {
+		if !users.Has(userKey) || keptSecretKeys[secretKey] {
+			continue
+		}
+		users.Delete(userKey)
+		if users.Len() == 0 {
+			delete(t.users, secretKey)
+			delete(t.namespaces, secretKey)
+			t.deleteAccessorLocked(secretKey)
+		}
+	}
+}
+
+func infraClusterUserKey(user client.Object) string {
+	return fmt.Sprintf("%T %s/%s", user, user.GetNamespace(), user.GetName())
+}
+
+// Watch starts watching the infra cluster referenced by the kubeconfig Secret, with the informers of the shared
+// cache. The watch is registered to the watcher only once per input name, and is moved to the new cache once the
+// cache is rebuilt, while the informers of the former cache are stopped along with it.
+func (t *InfraClusterTracker) Watch(ctx context.Context, infraClusterSecretRef *corev1.ObjectReference, input InfraClusterWatchInput) error {
+	accessor, err := t.getAccessor(ctx, infraClusterSecretRef, "")
+	if err != nil {
+		return err
+	}
+
+	secretKey := types.NamespacedName{Name: infraClusterSecretRef.Name, Namespace: infraClusterSecretRef.Namespace}
+	t.lock.Lock()
+	if _, ok := t.watches[secretKey][input.Name]; ok {
+		t.lock.Unlock()
+		return nil
+	}
+	if _, ok := t.watches[secretKey]; !ok {
+		t.watches[secretKey] = map[string]*infraClusterWatch{}
+	}
+	watch := &infraClusterWatch{kind: input.Kind, accessor: accessor}
+	t.watches[secretKey][input.Name] = watch
+	t.lock.Unlock()
+
+	// The watcher starts the watch right away once it is started, which must not happen with the lock of the tracker
+	// held, since the accessor of the watch is replaced with the lock held.
+	if err := input.Watcher.Watch(watch, input.EventHandler, input.Predicates...); err != nil {
+		t.lock.Lock()
+		delete(t.watches[secretKey], input.Name)
+		t.lock.Unlock()
+		return fmt.Errorf("watch %s: %s", input.Name, err)
+	}
+	return nil
+}
+
+func (t *InfraClusterTracker) getAccessor(ctx context.Context, infraClusterSecretRef *corev1.ObjectReference, namespace string) (*infraClusterAccessor, error) {
+	var infraClusterSecret corev1.Secret
+	infraClusterSecretKey := types.NamespacedName{
+		Name:      infraClusterSecretRef.Name,
+		Namespace: infraClusterSecretRef.Namespace,
+	}
+	if err := t.client.Get(ctx, infraClusterSecretKey, &infraClusterSecret); err != nil {
+		return nil, fmt.Errorf("get infra cluster kubeconfig Secret: %s", err)
+	}
+
+	secretLock := t.getSecretLock(infraClusterSecretKey)
+	secretLock.Lock()
+	defer secretLock.Unlock()
+
+	namespaces := t.addNamespace(infraClusterSecretKey, namespace)
+	if accessor := t.reuseAccessor(infraClusterSecretKey, &infraClusterSecret, namespaces); accessor != nil {
+		return accessor, nil
+	}
+
+	// The accessor is built without holding the lock of the tracker, so that the clients of other infra clusters are
+	// not blocked by an infra cluster being slow to respond.
+	accessor, err := t.newAccessor(ctx, &infraClusterSecret, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.deleteAccessorLocked(infraClusterSecretKey)
+	t.accessors[infraClusterSecretKey] = accessor
+	for name, watch := range t.watches[infraClusterSecretKey] {
+		if err := watch.setAccessor(accessor); err != nil {
+			ctrl.Log.WithName("infra-cluster-tracker").Error(err, "move watch to the rebuilt cache", "secret", infraClusterSecretKey, "watch", name)
+		}
+	}
+	go t.healthCheck(infraClusterSecretKey, accessor)
+	return accessor, nil
+}
+
+// addNamespace records the infra namespace used with the Secret, and returns all the infra namespaces used with the
+// Secret, which are watched by the cache of its accessor.
+func (t *InfraClusterTracker) addNamespace(key types.NamespacedName, namespace string) sets.String {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.namespaces[key]; !ok {
+		t.namespaces[key] = sets.NewString()
+	}
+	if namespace != "" {
+		t.namespaces[key].Insert(namespace)
+	}
+	return sets.NewString(t.namespaces[key].UnsortedList()...)
+}
+
+func (t *InfraClusterTracker) getSecretLock(key types.NamespacedName) *sync.Mutex {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.secretLocks[key]; !ok {
+		t.secretLocks[key] = &sync.Mutex{}
+	}
+	return t.secretLocks[key]
+}
+
+// reuseAccessor returns the existing accessor of the Secret if it is still valid for the Secret and watches all the
+// namespaces, or nil if the accessor has to be rebuilt.
+func (t *InfraClusterTracker) reuseAccessor(key types.NamespacedName, infraClusterSecret *corev1.Secret, namespaces sets.String) *infraClusterAccessor {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	accessor, ok := t.accessors[key]
+	if !ok || !accessor.namespaces.IsSuperset(namespaces) {
+		return nil
+	}
+	if accessor.secretResourceVersion == infraClusterSecret.ResourceVersion {
+		return accessor
+	}
+	if accessor.token != nil && isOnlyTokenRotated(accessor.secretData, infraClusterSecret.Data) {
+		accessor.token.Set(string(infraClusterSecret.Data[corev1.ServiceAccountTokenKey]))
+		accessor.secretResourceVersion = infraClusterSecret.ResourceVersion
+		accessor.secretData = infraClusterSecret.Data
+		return accessor
+	}
+	return nil
+}
+
+func (t *InfraClusterTracker) newAccessor(ctx context.Context, infraClusterSecret *corev1.Secret, namespaces sets.String) (*infraClusterAccessor, error) {
+	restConfig, token, err := buildInfraClusterRESTConfig(infraClusterSecret, t.allowExecPlugins, t.allowCredentialFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// The timeout is not set on the config of the cache, since it would also cut the long-running watch requests of
+	// the informers.
+	clientConfig := rest.CopyConfig(restConfig)
+	clientConfig.Timeout = infraClusterClientTimeout
+
+	// The REST mappings are discovered lazily, so that no request is sent to the infra cluster until it is used.
+	mapper, err := apiutil.NewDynamicRESTMapper(clientConfig, apiutil.WithLazyDiscovery)
+	if err != nil {
+		return nil, fmt.Errorf("create infra cluster REST mapper: %s", err)
+	}
+
+	// Namespaced objects are only watched in the infra namespaces in use, while cluster-scoped objects, i.e. Nodes, are
+	// watched by a cluster-wide informer.
+	infraClusterCache, err := cache.MultiNamespacedCacheBuilder(namespaces.List())(restConfig, cache.Options{Scheme: t.scheme, Mapper: mapper})
+	if err != nil {
+		return nil, fmt.Errorf("create infra cluster cache: %s", err)
+	}
+
+	uncachedClient, err := client.New(clientConfig, client.Options{Scheme: t.scheme, Mapper: mapper})
+	if err != nil {
+		return nil, fmt.Errorf("create infra cluster client: %s", err)
+	}
+
+	infraClusterClient, err := client.NewDelegatingClient(client.NewDelegatingClientInput{
+		CacheReader: infraClusterCacheReader{Reader: infraClusterCache},
+		Client:      uncachedClient,
+		// VM Pods are only read to report machine addresses and bootstrap data Secrets are only read to be synced,
+		// caching all Pods and Secrets of the infra cluster is not worth it.
+		UncachedObjects: []client.Object{&corev1.Pod{}, &corev1.Secret{}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create infra cluster delegating client: %s", err)
+	}
+
+	cacheCtx, cancel := context.WithCancel(context.Background())
+	go infraClusterCache.Start(cacheCtx) //nolint:errcheck
+	syncCtx, syncCancel := context.WithTimeout(ctx, infraClusterCacheSyncTimeout)
+	defer syncCancel()
+	if !infraClusterCache.WaitForCacheSync(syncCtx) {
+		cancel()
+		return nil, errors.New("wait for infra cluster cache to sync")
+	}
+
+	return &infraClusterAccessor{
+		secretResourceVersion: infraClusterSecret.ResourceVersion,
+		secretData:            infraClusterSecret.Data,
+		token:                 token,
+		config:                restConfig,
+		namespaces:            namespaces,
+		cache:                 infraClusterCache,
+		client:                infraClusterClient,
+		ctx:                   cacheCtx,
+		stop:                  cancel,
+	}, nil
+}
+
+// infraClusterWatch is the source of a watch on an infra cluster, which is registered to the watcher once and watches
+// the cache of the current accessor of the Secret.
+type infraClusterWatch struct {
+	kind client.Object
+
+	lock       sync.Mutex
+	accessor   *infraClusterAccessor
+	started    bool
+	handler    handler.EventHandler
+	queue      workqueue.RateLimitingInterface
+	predicates []predicate.Predicate
+}
+
+var _ source.Source = &infraClusterWatch{}
+
+// Start implements source.Source, and is called by the watcher to start watching.
+func (w *infraClusterWatch) Start(ctx context.Context, handler handler.EventHandler, queue workqueue.RateLimitingInterface, predicates ...predicate.Predicate) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.started = true
+	w.handler = handler
+	w.queue = queue
+	w.predicates = predicates
+	return w.startLocked()
+}
+
+func (w *infraClusterWatch) setAccessor(accessor *infraClusterAccessor) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.accessor = accessor
+	if !w.started {
+		return nil
+	}
+	return w.startLocked()
+}
+
+// startLocked watches the cache of the current accessor. The event handlers are stopped along with the cache.
+func (w *infraClusterWatch) startLocked() error {
+	ctx := w.accessor.ctx
+	src := source.NewKindWithCache(w.kind, w.accessor.cache)
+	if err := src.Start(ctx, w.handler, w.queue, w.predicates...); err != nil {
+		return err
+	}
+	go func() {
+		if err := src.WaitForSync(ctx); err != nil {
+			ctrl.Log.WithName("infra-cluster-tracker").Error(err, "wait for infra cluster watch to sync", "kind", fmt.Sprintf("%T", w.kind))
+		}
+	}()
+	return nil
+}
+
+func (w *infraClusterWatch) String() string {
+	return fmt.Sprintf("infra cluster kind source: %T", w.kind)
+}
+
+// infraClusterCacheReader bounds the reads of the cache, which otherwise block until the informer of a kind read for
+// the first time is synced, so that an unreachable infra cluster does not block the reconcilers forever.
+type infraClusterCacheReader struct {
+	client.Reader
+}
+
+func (r infraClusterCacheReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, cancel := context.WithTimeout(ctx, infraClusterCacheSyncTimeout)
+	defer cancel()
+	return r.Reader.Get(ctx, key, obj, opts...)
+}
+
+func (r infraClusterCacheReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, cancel := context.WithTimeout(ctx, infraClusterCacheSyncTimeout)
+	defer cancel()
+	return r.Reader.List(ctx, list, opts...)
+}
+
+func (t *InfraClusterTracker) deleteAccessor(key types.NamespacedName, accessor *infraClusterAccessor) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.accessors[key] != accessor {
+		return
+	}
+	t.deleteAccessorLocked(key)
+}
+
+func (t *InfraClusterTracker) deleteAccessorLocked(key types.NamespacedName) {
+	accessor, ok := t.accessors[key]
+	if !ok {
+		return
+	}
+	accessor.stop()
+	delete(t.accessors, key)
+}
+
+// healthCheck probes the infra cluster API server periodically, and drops the accessor after consecutive failures
+// so that the next GetClient builds a fresh one.
+func (t *InfraClusterTracker) healthCheck(key types.NamespacedName, accessor *infraClusterAccessor) {
+	log := ctrl.Log.WithName("infra-cluster-tracker").WithValues("secret", key)
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(accessor.config)
+	if err != nil {
+		log.Error(err, "create discovery client")
+		t.deleteAccessor(key, accessor)
+		return
+	}
+
+	failures := 0
+	ticker := time.NewTicker(infraClusterHealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-accessor.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), infraClusterHealthCheckTimeout)
+		err := discoveryClient.RESTClient().Get().AbsPath("/").Do(ctx).Error()
+		cancel()
+		if err != nil {
+			failures++
+			log.Error(err, "infra cluster health check failed", "failures", failures)
+			if failures >= infraClusterHealthCheckFailureThreshold {
+				t.deleteAccessor(key, accessor)
+				return
+			}
+			continue
+		}
+		failures = 0
+	}
+}
+
+// buildInfraClusterRESTConfig builds the REST config of the infra cluster, either from the kubeconfig key of the
+// Secret, or from the server, ca.crt and token or tls.crt/tls.key keys of the Secret. The returned token is non-nil for
+// token credentials, and is read by the REST config on every request so that it can be rotated in place.
+func buildInfraClusterRESTConfig(infraClusterSecret *corev1.Secret, allowExecPlugins bool, allowCredentialFiles bool) (*rest.Config, *infraClusterToken, error) {
+	if kubeConfig, ok := infraClusterSecret.Data[infraClusterKubeConfigKey]; ok {
+		config, err := clientcmd.Load(kubeConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load kubeconfig: %s", err)
+		}
+		if err := validateInfraClusterKubeConfig(config, allowExecPlugins, allowCredentialFiles); err != nil {
+			return nil, nil, err
+		}
+
+		restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, nil, fmt.Errorf("create REST config: %s", err)
+		}
+		return restConfig, nil, nil
+	}
+
+	server, ok := infraClusterSecret.Data[infraClusterServerKey]
+	if !ok {
+		return nil, nil, fmt.Errorf("retrieve infra cluster credentials from Secret: either '%s' or '%s' key is required", infraClusterKubeConfigKey, infraClusterServerKey)
+	}
+	restConfig := &rest.Config{
+		Host: string(server),
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: infraClusterSecret.Data[corev1.ServiceAccountRootCAKey],
+		},
+	}
+
+	if token, ok := infraClusterSecret.Data[corev1.ServiceAccountTokenKey]; ok {
+		infraClusterToken := &infraClusterToken{token: string(token)}
+		restConfig.WrapTransport = infraClusterToken.WrapTransport
+		return restConfig, infraClusterToken, nil
+	}
+
+	certData, hasCert := infraClusterSecret.Data[corev1.TLSCertKey]
+	keyData, hasKey := infraClusterSecret.Data[corev1.TLSPrivateKeyKey]
+	if !hasCert || !hasKey {
+		return nil, nil, fmt.Errorf("retrieve infra cluster credentials from Secret: either '%s' or '%s' and '%s' keys are required", corev1.ServiceAccountTokenKey, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+	restConfig.CertData = certData
+	restConfig.KeyData = keyData
+	return restConfig, nil, nil
+}
+
+// validateInfraClusterKubeConfig rejects the credentials of the kubeconfig that are not contained in the kubeconfig
+// itself, i.e. exec plugins and auth providers unless allowExecPlugins is set, and file paths unless
+// allowCredentialFiles is set.
+func validateInfraClusterKubeConfig(config *clientcmdapi.Config, allowExecPlugins bool, allowCredentialFiles bool) error {
+	for name, authInfo := range config.AuthInfos {
+		if !allowExecPlugins {
+			if authInfo.Exec != nil {
+				return fmt.Errorf("kubeconfig user %q: exec plugins are not allowed", name)
+			}
+			if authInfo.AuthProvider != nil {
+				return fmt.Errorf("kubeconfig user %q: auth providers are not allowed", name)
+			}
+		}
+		if !allowCredentialFiles && (authInfo.ClientCertificate != "" || authInfo.ClientKey != "" || authInfo.TokenFile != "") {
+			return fmt.Errorf("kubeconfig user %q: credential files are not allowed", name)
+		}
+	}
+	if !allowCredentialFiles {
+		for name, cluster := range config.Clusters {
+			if cluster.CertificateAuthority != "" {
+				return fmt.Errorf("kubeconfig cluster %q: credential files are not allowed", name)
+			}
+		}
+	}
+	return nil
+}
+
+// isOnlyTokenRotated returns whether the token key is the only difference between the data of the structured
+// credentials Secret.
+func isOnlyTokenRotated(oldData map[string][]byte, newData map[string][]byte) bool {
+	if _, ok := newData[corev1.ServiceAccountTokenKey]; !ok {
+		return false
+	}
+	if len(oldData) != len(newData) {
+		return false
+	}
+	for key, value := range newData {
+		if key == corev1.ServiceAccountTokenKey {
+			continue
+		}
+		oldValue, ok := oldData[key]
+		if !ok || !bytes.Equal(oldValue, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// infraClusterToken is a bearer token of an infra cluster that can be rotated without rebuilding the clients.
+type infraClusterToken struct {
+	lock  sync.RWMutex
+	token string
+}
+
+// Get returns the current token.
+func (t *infraClusterToken) Get() string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.token
+}
+
+// Set replaces the token used by subsequent requests.
+func (t *infraClusterToken) Set(token string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.token = token
+}
+
+// WrapTransport sets the current token as the bearer token of every request.
+func (t *infraClusterToken) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = utilnet.CloneRequest(req)
+		req.Header.Set("Authorization", "Bearer "+t.Get())
+		return rt.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	infrastructurev1beta1 "github.com/smartxworks/cluster-api-provider-virtink/api/v1beta1"
+)
+
+var _ = Describe("InfraClusterTracker", func() {
+	var tracker *InfraClusterTracker
+	var user *infrastructurev1beta1.VirtinkCluster
+	var secret corev1.Secret
+	BeforeEach(func() {
+		tracker = NewInfraClusterTracker(k8sClient, k8sClient.Scheme(), false, false)
+		user = &infrastructurev1beta1.VirtinkCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cluster-" + uuid.New().String(),
+				Namespace: "default",
+			},
+		}
+
+		kubeConfig := clientcmdapi.NewConfig()
+		kubeConfig.Clusters["infra"] = &clientcmdapi.Cluster{
+			Server:                   cfg.Host,
+			CertificateAuthorityData: cfg.CAData,
+		}
+		kubeConfig.AuthInfos["infra"] = &clientcmdapi.AuthInfo{
+			ClientCertificateData: cfg.CertData,
+			ClientKeyData:         cfg.KeyData,
+		}
+		kubeConfig.Contexts["infra"] = &clientcmdapi.Context{
+			Cluster:  "infra",
+			AuthInfo: "infra",
+		}
+		kubeConfig.CurrentContext = "infra"
+		kubeConfigData, err := clientcmd.Write(*kubeConfig)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "infra-cluster-" + uuid.New().String(),
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"kubeconfig": kubeConfigData,
+			},
+		}
+		Expect(k8sClient.Create(ctx, &secret)).To(Succeed())
+	})
+
+	It("should reuse the client until the Secret changes", func() {
+		secretRef := &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}
+		infraClusterClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+
+		var namespace corev1.Namespace
+		Eventually(func() error {
+			return infraClusterClient.Get(ctx, types.NamespacedName{Name: "default"}, &namespace)
+		}).Should(Succeed())
+
+		sameClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sameClient).To(BeIdenticalTo(infraClusterClient))
+
+		secret.Labels = map[string]string{"updated": "true"}
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+		newClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newClient).NotTo(BeIdenticalTo(infraClusterClient))
+	})
+
+	It("should drop the client once all users are released", func() {
+		secretRef := &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}
+		infraClusterClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		anotherUser := &infrastructurev1beta1.VirtinkMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      user.Name,
+				Namespace: user.Namespace,
+			},
+		}
+		sameClient, err := tracker.GetClient(ctx, anotherUser, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sameClient).To(BeIdenticalTo(infraClusterClient))
+
+		tracker.ReleaseClients(user)
+		sameClient, err = tracker.GetClient(ctx, anotherUser, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sameClient).To(BeIdenticalTo(infraClusterClient))
+
+		tracker.ReleaseClients(anotherUser)
+		newClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newClient).NotTo(BeIdenticalTo(infraClusterClient))
+	})
+
+	It("should only watch the namespaces in use", func() {
+		secretRef := &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}
+		infraClusterClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+
+		var configMaps corev1.ConfigMapList
+		Expect(infraClusterClient.List(ctx, &configMaps, client.InNamespace("default"))).To(Succeed())
+		Expect(infraClusterClient.List(ctx, &configMaps, client.InNamespace("kube-system"))).NotTo(Succeed())
+
+		newClient, err := tracker.GetClient(ctx, user, secretRef, "kube-system")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newClient).NotTo(BeIdenticalTo(infraClusterClient))
+		Expect(newClient.List(ctx, &configMaps, client.InNamespace("default"))).To(Succeed())
+		Expect(newClient.List(ctx, &configMaps, client.InNamespace("kube-system"))).To(Succeed())
+	})
+
+	It("should drop the client once its user switches to another Secret", func() {
+		anotherSecret := corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "infra-cluster-" + uuid.New().String(),
+				Namespace: "default",
+			},
+			Data: secret.Data,
+		}
+		Expect(k8sClient.Create(ctx, &anotherSecret)).To(Succeed())
+
+		secretRef := &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}
+		_, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		anotherSecretRef := &corev1.ObjectReference{Name: anotherSecret.Name, Namespace: anotherSecret.Namespace}
+		_, err = tracker.GetClient(ctx, user, anotherSecretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+
+		tracker.ReleaseClients(user, anotherSecretRef)
+		Expect(tracker.accessors).NotTo(HaveKey(types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}))
+		Expect(tracker.accessors).To(HaveKey(types.NamespacedName{Name: anotherSecret.Name, Namespace: anotherSecret.Namespace}))
+	})
+
+	It("should move the watches to the rebuilt cache", func() {
+		secretRef := &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}
+		_, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+
+		watcher := &fakeWatcher{queue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())}
+		defer watcher.queue.ShutDown()
+		input := InfraClusterWatchInput{
+			Name:         "configmap",
+			Watcher:      watcher,
+			Kind:         &corev1.ConfigMap{},
+			EventHandler: &handler.EnqueueRequestForObject{},
+		}
+		Expect(tracker.Watch(ctx, secretRef, input)).To(Succeed())
+
+		secret.Labels = map[string]string{"updated": "true"}
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+		_, err = tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracker.Watch(ctx, secretRef, input)).To(Succeed())
+		Expect(watcher.watches).To(Equal(1))
+
+		configMap := corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "config-" + uuid.New().String(),
+				Namespace: "default",
+			},
+		}
+		Expect(k8sClient.Create(ctx, &configMap)).To(Succeed())
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: configMap.Name, Namespace: configMap.Namespace}}
+		Eventually(func() bool {
+			for watcher.queue.Len() > 0 {
+				item, _ := watcher.queue.Get()
+				watcher.queue.Done(item)
+				if item == request {
+					return true
+				}
+			}
+			return false
+		}).Should(BeTrue())
+	})
+
+	It("should fail when the kubeconfig key is missing", func() {
+		delete(secret.Data, "kubeconfig")
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+		_, err := tracker.GetClient(ctx, user, &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}, "default")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject kubeconfig with exec plugins", func() {
+		kubeConfig, err := clientcmd.Load(secret.Data["kubeconfig"])
+		Expect(err).NotTo(HaveOccurred())
+		kubeConfig.AuthInfos["infra"].Exec = &clientcmdapi.ExecConfig{
+			APIVersion: "client.authentication.k8s.io/v1",
+			Command:    "/bin/sh",
+		}
+		secret.Data["kubeconfig"], err = clientcmd.Write(*kubeConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+
+		_, err = tracker.GetClient(ctx, user, &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}, "default")
+		Expect(err).To(MatchError(ContainSubstring("exec plugins are not allowed")))
+	})
+
+	It("should reject kubeconfig with auth providers", func() {
+		kubeConfig, err := clientcmd.Load(secret.Data["kubeconfig"])
+		Expect(err).NotTo(HaveOccurred())
+		kubeConfig.AuthInfos["infra"].AuthProvider = &clientcmdapi.AuthProviderConfig{
+			Name: "oidc",
+		}
+		secret.Data["kubeconfig"], err = clientcmd.Write(*kubeConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+
+		_, err = tracker.GetClient(ctx, user, &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}, "default")
+		Expect(err).To(MatchError(ContainSubstring("auth providers are not allowed")))
+	})
+
+	credentialFiles := map[string]func(kubeConfig *clientcmdapi.Config){
+		"token file": func(kubeConfig *clientcmdapi.Config) {
+			kubeConfig.AuthInfos["infra"].TokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+		},
+		"client certificate file": func(kubeConfig *clientcmdapi.Config) {
+			kubeConfig.AuthInfos["infra"].ClientCertificate = "/etc/kubernetes/pki/admin.crt"
+		},
+		"client key file": func(kubeConfig *clientcmdapi.Config) {
+			kubeConfig.AuthInfos["infra"].ClientKey = "/etc/kubernetes/pki/admin.key"
+		},
+		"certificate authority file": func(kubeConfig *clientcmdapi.Config) {
+			kubeConfig.Clusters["infra"].CertificateAuthority = "/etc/kubernetes/pki/ca.crt"
+		},
+	}
+	for name, setFile := range credentialFiles {
+		setFile := setFile
+		It("should reject kubeconfig with "+name, func() {
+			kubeConfig, err := clientcmd.Load(secret.Data["kubeconfig"])
+			Expect(err).NotTo(HaveOccurred())
+			setFile(kubeConfig)
+			secret.Data["kubeconfig"], err = clientcmd.Write(*kubeConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+
+			_, err = tracker.GetClient(ctx, user, &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}, "default")
+			Expect(err).To(MatchError(ContainSubstring("credential files are not allowed")))
+		})
+	}
+
+	It("should allow credential files if enabled", func() {
+		tracker = NewInfraClusterTracker(k8sClient, k8sClient.Scheme(), false, true)
+		kubeConfig, err := clientcmd.Load(secret.Data["kubeconfig"])
+		Expect(err).NotTo(HaveOccurred())
+		kubeConfig.AuthInfos["infra"].TokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+		secret.Data["kubeconfig"], err = clientcmd.Write(*kubeConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+
+		_, err = tracker.GetClient(ctx, user, &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}, "default")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should build the client from client certificate keys", func() {
+		secret.Data = map[string][]byte{
+			"server":                       []byte(cfg.Host),
+			corev1.ServiceAccountRootCAKey: cfg.CAData,
+			corev1.TLSCertKey:              cfg.CertData,
+			corev1.TLSPrivateKeyKey:        cfg.KeyData,
+		}
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+
+		infraClusterClient, err := tracker.GetClient(ctx, user, &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}, "default")
+		Expect(err).NotTo(HaveOccurred())
+		var namespace corev1.Namespace
+		Eventually(func() error {
+			return infraClusterClient.Get(ctx, types.NamespacedName{Name: "default"}, &namespace)
+		}).Should(Succeed())
+	})
+
+	It("should keep the client when only the token is rotated", func() {
+		var authorization atomic.Value
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization.Store(r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("{}"))
+		}))
+		defer server.Close()
+
+		secret.Data = map[string][]byte{
+			"server":                      []byte(server.URL),
+			corev1.ServiceAccountTokenKey: []byte("token-1"),
+		}
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+		secretRef := &corev1.ObjectReference{Name: secret.Name, Namespace: secret.Namespace}
+		infraClusterClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+
+		tracker.lock.Lock()
+		httpClient, err := rest.HTTPClientFor(tracker.accessors[types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}].config)
+		tracker.lock.Unlock()
+		Expect(err).NotTo(HaveOccurred())
+		getAuthorization := func() string {
+			resp, err := httpClient.Get(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Body.Close()).To(Succeed())
+			return authorization.Load().(string)
+		}
+		Expect(getAuthorization()).To(Equal("Bearer token-1"))
+
+		secret.Data[corev1.ServiceAccountTokenKey] = []byte("token-2")
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+		sameClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sameClient).To(BeIdenticalTo(infraClusterClient))
+		Expect(getAuthorization()).To(Equal("Bearer token-2"))
+
+		secret.Data["server"] = []byte("https://127.0.0.1:1")
+		Expect(k8sClient.Update(ctx, &secret)).To(Succeed())
+		newClient, err := tracker.GetClient(ctx, user, secretRef, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newClient).NotTo(BeIdenticalTo(infraClusterClient))
+	})
+})
+
+// fakeWatcher starts the watched sources right away, as a started controller does.
+type fakeWatcher struct {
+	queue   workqueue.RateLimitingInterface
+	watches int
+}
+
+func (w *fakeWatcher) Watch(src source.Source, eventhandler handler.EventHandler, predicates ...predicate.Predicate) error {
+	w.watches++
+	return src.Start(ctx, eventhandler, w.queue, predicates...)
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// defaultMACAddressPrefix is the OUI of QEMU/KVM virtual NICs.
+var defaultMACAddressPrefix = net.HardwareAddr{0x52, 0x54, 0x00}
+
+// ParseMACAddressPrefix parses the prefix of the MAC addresses allocated to the interfaces of VMs, e.g. an OUI. The
+// prefix must leave room for at least one byte of the MAC address and must not be a multicast address.
+func ParseMACAddressPrefix(s string) (net.HardwareAddr, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 5 {
+		return nil, fmt.Errorf("MAC address prefix %q must be 1 to 5 bytes", s)
+	}
+	prefix := make(net.HardwareAddr, 0, len(parts))
+	for _, part := range parts {
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil || len(part) != 2 {
+			return nil, fmt.Errorf("invalid MAC address prefix %q", s)
+		}
+		prefix = append(prefix, byte(b))
+	}
+	if prefix[0]&0x01 != 0 {
+		return nil, fmt.Errorf("MAC address prefix %q is a multicast address", s)
+	}
+	return prefix, nil
+}
+
+// generateMAC generates a random MAC address with the prefix.
+func generateMAC(prefix net.HardwareAddr) (net.HardwareAddr, error) {
+	suffix := make([]byte, 6-len(prefix))
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("rand: %s", err)
+	}
+	return net.HardwareAddr(append(append([]byte{}, prefix...), suffix...)), nil
+}
+
+// deriveMAC derives a MAC address with the prefix from the hash of the seed, so that the same seed always results in
+// the same MAC address.
+func deriveMAC(prefix net.HardwareAddr, seed string) net.HardwareAddr {
+	hash := sha256.Sum256([]byte(seed))
+	return net.HardwareAddr(append(append([]byte{}, prefix...), hash[:6-len(prefix)]...))
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MAC address", func() {
+	It("should parse MAC address prefix", func() {
+		prefix, err := ParseMACAddressPrefix("02:00:5e")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prefix).To(Equal(net.HardwareAddr{0x02, 0x00, 0x5e}))
+
+		_, err = ParseMACAddressPrefix("")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseMACAddressPrefix("02:00:5e:00:00:01")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseMACAddressPrefix("01:00:5e")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should generate MAC address with prefix", func() {
+		mac, err := generateMAC(net.HardwareAddr{0x02, 0x00, 0x5e, 0x10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mac).To(HaveLen(6))
+		Expect(mac.String()).To(HavePrefix("02:00:5e:10:"))
+	})
+
+	It("should derive the same MAC address from the same seed", func() {
+		prefix := net.HardwareAddr{0x52, 0x54, 0x00}
+		mac := deriveMAC(prefix, "default/cluster/machine/pod")
+		Expect(mac).To(HaveLen(6))
+		Expect(mac.String()).To(HavePrefix("52:54:00:"))
+		Expect(deriveMAC(prefix, "default/cluster/machine/pod")).To(Equal(mac))
+		Expect(deriveMAC(prefix, "default/cluster/machine/storage")).NotTo(Equal(mac))
+	})
+})
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"fmt"
+	"net"
+
+	"sigs.k8s.io/yaml"
+)
+
+// interfaceAddress is an IP address allocated to an interface of the VM, by either the metal3 IPAM or a Cluster API
+// IPAM provider.
+type interfaceAddress struct {
+	InterfaceName string
+	MAC           net.HardwareAddr
+	Address       string
+	Prefix        int
+	Gateway       string
+	DNSServers    []string
+}
+
+// dhcpInterface is an interface of the VM no IP address is allocated to, which is configured by DHCP.
+type dhcpInterface struct {
+	InterfaceName string
+	MAC           net.HardwareAddr
+}
+
+// networkConfig is the cloud-init network config version 2, see
+// https://cloudinit.readthedocs.io/en/latest/reference/network-config-format-v2.html.
+type networkConfig struct {
+	Version   int                              `json:"version"`
+	Ethernets map[string]networkConfigEthernet `json:"ethernets"`
+}
+
+type networkConfigEthernet struct {
+	Match       networkConfigMatch        `json:"match"`
+	DHCP4       bool                      `json:"dhcp4,omitempty"`
+	DHCP6       bool                      `json:"dhcp6,omitempty"`
+	Addresses   []string                  `json:"addresses,omitempty"`
+	Routes      []networkConfigRoute      `json:"routes,omitempty"`
+	Nameservers *networkConfigNameservers `json:"nameservers,omitempty"`
+}
+
+type networkConfigMatch struct {
+	MACAddress string `json:"macaddress"`
+}
+
+type networkConfigRoute struct {
+	To  string `json:"to"`
+	Via string `json:"via"`
+}
+
+type networkConfigNameservers struct {
+	Addresses []string `json:"addresses"`
+}
+
+// buildNetworkData renders the cloud-init network config of the VM from the IP addresses allocated to its interfaces.
+// The interfaces are matched by MAC address, since the interface names in the guest are not known in advance. Only the
+// interface of the first address, i.e. the primary one, gets the default routes, so that the gateways of the other
+// interfaces do not compete with them, and each DNS server is only configured once. The other interfaces are
+// configured by DHCP, since the fallback DHCP config of cloud-init is not applied once a network config is given.
+func buildNetworkData(addresses []interfaceAddress, dhcpInterfaces []dhcpInterface) (string, error) {
+	config := networkConfig{
+		Version:   2,
+		Ethernets: map[string]networkConfigEthernet{},
+	}
+	defaultRoutes := map[string]bool{}
+	dnsServers := map[string]bool{}
+	for _, address := range addresses {
+		ethernet := config.Ethernets[address.InterfaceName]
+		ethernet.Match.MACAddress = address.MAC.String()
+
+		ip := net.ParseIP(address.Address)
+		if ip == nil {
+			return "", fmt.Errorf("invalid IP address %q", address.Address)
+		}
+		prefix := address.Prefix
+		if prefix == 0 {
+			prefix = 32
+			if ip.To4() == nil {
+				prefix = 128
+			}
+		}
+		ethernet.Addresses = append(ethernet.Addresses, fmt.Sprintf("%s/%d", ip, prefix))
+
+		to := "0.0.0.0/0"
+		if ip.To4() == nil {
+			to = "::/0"
+		}
+		if address.Gateway != "" && address.InterfaceName == addresses[0].InterfaceName && !defaultRoutes[to] {
+			ethernet.Routes = append(ethernet.Routes, networkConfigRoute{
+				To:  to,
+				Via: address.Gateway,
+			})
+			defaultRoutes[to] = true
+		}
+
+		for _, dnsServer := range address.DNSServers {
+			if dnsServers[dnsServer] {
+				continue
+			}
+			if ethernet.Nameservers == nil {
+				ethernet.Nameservers = &networkConfigNameservers{}
+			}
+			ethernet.Nameservers.Addresses = append(ethernet.Nameservers.Addresses, dnsServer)
+			dnsServers[dnsServer] = true
+		}
+		config.Ethernets[address.InterfaceName] = ethernet
+	}
+
+	for _, iface := range dhcpInterfaces {
+		config.Ethernets[iface.InterfaceName] = networkConfigEthernet{
+			Match: networkConfigMatch{
+				MACAddress: iface.MAC.String(),
+			},
+			DHCP4: true,
+			DHCP6: true,
+		}
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("marshal network config: %s", err)
+	}
+	return string(data), nil
+}
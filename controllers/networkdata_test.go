@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("buildNetworkData", func() {
+	It("should render network config matched by MAC address", func() {
+		mac, err := net.ParseMAC("52:54:00:12:34:56")
+		Expect(err).NotTo(HaveOccurred())
+		networkData, err := buildNetworkData([]interfaceAddress{{
+			InterfaceName: "pod",
+			MAC:           mac,
+			Address:       "10.0.0.10",
+			Prefix:        24,
+			Gateway:       "10.0.0.1",
+			DNSServers:    []string{"8.8.8.8"},
+		}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkData).To(MatchYAML(`
+version: 2
+ethernets:
+  pod:
+    match:
+      macaddress: "52:54:00:12:34:56"
+    addresses:
+    - 10.0.0.10/24
+    routes:
+    - to: 0.0.0.0/0
+      via: 10.0.0.1
+    nameservers:
+      addresses:
+      - 8.8.8.8
+`))
+	})
+
+	It("should render addresses of multiple interfaces", func() {
+		podMAC, err := net.ParseMAC("52:54:00:12:34:56")
+		Expect(err).NotTo(HaveOccurred())
+		storageMAC, err := net.ParseMAC("52:54:00:12:34:57")
+		Expect(err).NotTo(HaveOccurred())
+		networkData, err := buildNetworkData([]interfaceAddress{{
+			InterfaceName: "pod",
+			MAC:           podMAC,
+			Address:       "10.0.0.10",
+			Prefix:        24,
+		}, {
+			InterfaceName: "pod",
+			MAC:           podMAC,
+			Address:       "fd00::10",
+			Prefix:        64,
+		}, {
+			InterfaceName: "storage",
+			MAC:           storageMAC,
+			Address:       "192.168.0.10",
+			Prefix:        24,
+		}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkData).To(MatchYAML(`
+version: 2
+ethernets:
+  pod:
+    match:
+      macaddress: "52:54:00:12:34:56"
+    addresses:
+    - 10.0.0.10/24
+    - fd00::10/64
+  storage:
+    match:
+      macaddress: "52:54:00:12:34:57"
+    addresses:
+    - 192.168.0.10/24
+`))
+	})
+
+	It("should only route the primary interface by default", func() {
+		podMAC, err := net.ParseMAC("52:54:00:12:34:56")
+		Expect(err).NotTo(HaveOccurred())
+		storageMAC, err := net.ParseMAC("52:54:00:12:34:57")
+		Expect(err).NotTo(HaveOccurred())
+		networkData, err := buildNetworkData([]interfaceAddress{{
+			InterfaceName: "pod",
+			MAC:           podMAC,
+			Address:       "10.0.0.10",
+			Prefix:        24,
+			Gateway:       "10.0.0.1",
+			DNSServers:    []string{"8.8.8.8"},
+		}, {
+			InterfaceName: "storage",
+			MAC:           storageMAC,
+			Address:       "192.168.0.10",
+			Prefix:        24,
+			Gateway:       "192.168.0.1",
+			DNSServers:    []string{"8.8.8.8", "8.8.4.4"},
+		}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkData).To(MatchYAML(`
+version: 2
+ethernets:
+  pod:
+    match:
+      macaddress: "52:54:00:12:34:56"
+    addresses:
+    - 10.0.0.10/24
+    routes:
+    - to: 0.0.0.0/0
+      via: 10.0.0.1
+    nameservers:
+      addresses:
+      - 8.8.8.8
+  storage:
+    match:
+      macaddress: "52:54:00:12:34:57"
+    addresses:
+    - 192.168.0.10/24
+    nameservers:
+      addresses:
+      - 8.8.4.4
+`))
+	})
+
+	It("should configure interfaces without IP addresses by DHCP", func() {
+		podMAC, err := net.ParseMAC("52:54:00:12:34:56")
+		Expect(err).NotTo(HaveOccurred())
+		storageMAC, err := net.ParseMAC("52:54:00:12:34:57")
+		Expect(err).NotTo(HaveOccurred())
+		networkData, err := buildNetworkData([]interfaceAddress{{
+			InterfaceName: "pod",
+			MAC:           podMAC,
+			Address:       "10.0.0.10",
+			Prefix:        24,
+		}}, []dhcpInterface{{
+			InterfaceName: "storage",
+			MAC:           storageMAC,
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkData).To(MatchYAML(`
+version: 2
+ethernets:
+  pod:
+    match:
+      macaddress: "52:54:00:12:34:56"
+    addresses:
+    - 10.0.0.10/24
+  storage:
+    match:
+      macaddress: "52:54:00:12:34:57"
+    dhcp4: true
+    dhcp6: true
+`))
+	})
+
+	It("should quote MAC addresses consisting of digits", func() {
+		// Unquoted, such MAC addresses would be read as sexagesimal numbers by YAML 1.1 parsers of cloud-init.
+		mac, err := net.ParseMAC("52:54:00:12:34:56")
+		Expect(err).NotTo(HaveOccurred())
+		networkData, err := buildNetworkData([]interfaceAddress{{
+			InterfaceName: "pod",
+			MAC:           mac,
+			Address:       "10.0.0.10",
+		}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkData).To(ContainSubstring(`macaddress: "52:54:00:12:34:56"`))
+	})
+
+	It("should default the prefix to a single address", func() {
+		mac, err := net.ParseMAC("52:54:00:12:34:56")
+		Expect(err).NotTo(HaveOccurred())
+		networkData, err := buildNetworkData([]interfaceAddress{{
+			InterfaceName: "pod",
+			MAC:           mac,
+			Address:       "fd00::10",
+		}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networkData).To(ContainSubstring("fd00::10/128"))
+	})
+})
@@ -8,12 +8,18 @@ import (
 	"path/filepath"
 	"testing"
 
+	ipamv1 "github.com/metal3-io/ip-address-manager/api/v1alpha1"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	virtv1alpha1 "github.com/smartxworks/virtink/pkg/apis/virt/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capiipamv1alpha1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -58,6 +64,7 @@ var _ = BeforeSuite(func() {
 			filepath.Join("..", "config", "crd", "bases"),
 			filepath.Join(capiModulePath, "config", "crd", "bases"),
 			filepath.Join(virtinkModulePath, "deploy", "crd"),
+			filepath.Join("testdata", "crd"),
 		},
 		ErrorIfCRDPathMissing: true,
 	}
@@ -73,6 +80,12 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	err = virtv1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = capiipamv1alpha1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = cdiv1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = ipamv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 
 	//+kubebuilder:scaffold:scheme
 
@@ -85,18 +98,28 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).ToNot(HaveOccurred())
 
+	tracker := NewInfraClusterTracker(k8sManager.GetClient(), k8sManager.GetScheme(), false, false)
+
 	err = (&VirtinkClusterReconciler{
 		Client:   k8sManager.GetClient(),
 		Scheme:   k8sManager.GetScheme(),
 		Recorder: k8sManager.GetEventRecorderFor("capch-controller-manager"),
-	}).SetupWithManager(k8sManager)
+		Tracker:  tracker,
+	}).SetupWithManager(ctx, k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&VirtinkMachineReconciler{
 		Client:   k8sManager.GetClient(),
 		Scheme:   k8sManager.GetScheme(),
 		Recorder: k8sManager.GetEventRecorderFor("capch-controller-manager"),
-	}).SetupWithManager(k8sManager)
+		Tracker:  tracker,
+	}).SetupWithManager(ctx, k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&VirtinkMachineTemplateReconciler{
+		Client: k8sManager.GetClient(),
+		Scheme: k8sManager.GetScheme(),
+	}).SetupWithManager(ctx, k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
 	go func() {
@@ -129,3 +152,21 @@ func getModulePath(name string) (string, error) {
 	}
 	return module.Dir, nil
 }
+
+// setBootstrapDataSecret creates a bootstrap data Secret of the given data and sets it on the Machine.
+func setBootstrapDataSecret(machineKey types.NamespacedName, data map[string]string) {
+	var machine capiv1beta1.Machine
+	Expect(k8sClient.Get(ctx, machineKey, &machine)).To(Succeed())
+	secretName := machine.Name + "-" + "secret"
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: machine.Namespace,
+		},
+		StringData: data,
+	}
+	Expect(k8sClient.Create(ctx, &secret)).To(Succeed())
+
+	machine.Spec.Bootstrap.DataSecretName = &secretName
+	Expect(k8sClient.Update(ctx, &machine)).To(Succeed())
+}
@@ -4,41 +4,80 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
+	ipamv1 "github.com/metal3-io/ip-address-manager/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/record"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	capiutil "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	capipatch "sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	infrastructurev1beta1 "github.com/smartxworks/cluster-api-provider-virtink/api/v1beta1"
 )
 
-const finalizer = "capch.cluster.x-k8s.io"
+const (
+	finalizer = "capch.cluster.x-k8s.io"
+
+	virtinkMachineNameLabel      = "capch.cluster.x-k8s.io/virtink-machine-name"
+	virtinkMachineNamespaceLabel = "capch.cluster.x-k8s.io/virtink-machine-namespace"
+	virtinkClusterNameLabel      = "capch.cluster.x-k8s.io/virtink-cluster-name"
+	virtinkClusterNamespaceLabel = "capch.cluster.x-k8s.io/virtink-cluster-namespace"
+
+	controlPlaneServiceFieldManager = "capch-controller-manager"
+
+	defaultAPIServerPort = 6443
+
+	// The infra clusters of failure domains are not watched, so the unreachable ones are checked again periodically.
+	unreachableFailureDomainRequeueInterval = 30 * time.Second
+)
 
 // VirtinkClusterReconciler reconciles a VirtinkCluster object
 type VirtinkClusterReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	Tracker  *InfraClusterTracker
+
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
+
+	controller controller.Controller
 }
 
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=virtinkclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=virtinkclusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=virtinkclusters/finalizers,verbs=update
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=virtinkclusteridentities,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;update;patch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters/status,verbs=get
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ipam.metal3.io,resources=ipclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ipam.metal3.io,resources=ipaddresses,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -49,182 +88,715 @@ type VirtinkClusterReconciler struct {
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.11.2/pkg/reconcile
-func (r *VirtinkClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *VirtinkClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, rerr error) {
 	var cluster infrastructurev1beta1.VirtinkCluster
 	if err := r.Get(ctx, req.NamespacedName, &cluster); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// The owner Cluster may be gone already while the VirtinkCluster is being deleted.
+	ownerCluster, err := capiutil.GetOwnerCluster(ctx, r.Client, cluster.ObjectMeta)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("get owner Cluster: %s", err)
+	}
+	if annotations.HasPaused(&cluster) || (ownerCluster != nil && ownerCluster.Spec.Paused) {
+		ctrl.LoggerFrom(ctx).Info("reconciliation is paused for this object")
+		return ctrl.Result{}, nil
+	}
+
 	patchHelper, err := capipatch.NewHelper(&cluster, r.Client)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("create Cluster patch helper: %s", err)
 	}
 
-	if err := r.reconcile(ctx, &cluster); err != nil {
+	defer func() {
+		conditions.SetSummary(&cluster, conditions.WithConditions(
+			infrastructurev1beta1.InfraClusterReachableCondition,
+			infrastructurev1beta1.ControlPlaneServiceReadyCondition,
+			infrastructurev1beta1.ControlPlaneServiceSyncedCondition,
+			infrastructurev1beta1.ControlPlaneEndpointReadyCondition,
+			infrastructurev1beta1.IPAddressAllocatedCondition,
+		))
+		if err := patchHelper.Patch(ctx, &cluster, capipatch.WithOwnedConditions{Conditions: []capiv1beta1.ConditionType{
+			capiv1beta1.ReadyCondition,
+			infrastructurev1beta1.InfraClusterReachableCondition,
+			infrastructurev1beta1.FailureDomainsReachableCondition,
+			infrastructurev1beta1.ControlPlaneServiceReadyCondition,
+			infrastructurev1beta1.ControlPlaneServiceSyncedCondition,
+			infrastructurev1beta1.ControlPlaneEndpointReadyCondition,
+			infrastructurev1beta1.IPAddressAllocatedCondition,
+		}}); err != nil {
+			if rerr == nil {
+				rerr = fmt.Errorf("patch Cluster: %s", err)
+			}
+		}
+	}()
+
+	if err := r.reconcile(ctx, &cluster, ownerCluster); err != nil {
+		reconcileErr := reconcileError{}
+		if errors.As(err, &reconcileErr) {
+			return reconcileErr.Result, rerr
+		}
 		return ctrl.Result{}, err
 	}
-
-	if err := patchHelper.Patch(ctx, &cluster); err != nil {
-		return ctrl.Result{}, fmt.Errorf("patch Cluster: %s", err)
+	if cluster.DeletionTimestamp.IsZero() && conditions.IsFalse(&cluster, infrastructurev1beta1.FailureDomainsReachableCondition) {
+		return ctrl.Result{RequeueAfter: unreachableFailureDomainRequeueInterval}, rerr
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, rerr
 }
 
-func (r *VirtinkClusterReconciler) reconcile(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster) error {
+func (r *VirtinkClusterReconciler) reconcile(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster, ownerCluster *capiv1beta1.Cluster) error {
+	infraNamespace := cluster.Namespace
+	if cluster.Spec.ControlPlaneServiceTemplate.ObjectMeta.Namespace != "" {
+		infraNamespace = cluster.Spec.ControlPlaneServiceTemplate.ObjectMeta.Namespace
+	}
+
 	infraClusterClient := r.Client
+	var infraClusterSecretRef *corev1.ObjectReference
 	if controllerutil.ContainsFinalizer(cluster, finalizer) {
-		if cluster.Spec.InfraClusterSecretRef != nil {
-			c, err := buildInfraClusterClient(ctx, r.Client, cluster.Spec.InfraClusterSecretRef)
+		ref, err := getInfraClusterSecretRef(ctx, r.Client, cluster, !cluster.DeletionTimestamp.IsZero())
+		if err != nil {
+			markInfraClusterUnreachable(cluster, err)
+			return fmt.Errorf("get infra cluster secret ref: %s", err)
+		}
+		infraClusterSecretRef = ref
+		if infraClusterSecretRef != nil {
+			c, err := r.Tracker.GetClient(ctx, cluster, infraClusterSecretRef, infraNamespace)
 			if err != nil {
+				conditions.MarkFalse(cluster, infrastructurev1beta1.InfraClusterReachableCondition, infrastructurev1beta1.InfraClusterClientFailedReason, capiv1beta1.ConditionSeverityError, err.Error())
 				return fmt.Errorf("build infra cluster client: %s", err)
 			}
 			infraClusterClient = c
+
+			if err := r.Tracker.Watch(ctx, infraClusterSecretRef, InfraClusterWatchInput{
+				Name:         "virtinkcluster-service",
+				Watcher:      r.controller,
+				Kind:         &corev1.Service{},
+				EventHandler: handler.EnqueueRequestsFromMapFunc(infraObjectToVirtinkCluster),
+			}); err != nil {
+				conditions.MarkFalse(cluster, infrastructurev1beta1.InfraClusterReachableCondition, infrastructurev1beta1.InfraClusterClientFailedReason, capiv1beta1.ConditionSeverityError, err.Error())
+				return fmt.Errorf("watch infra cluster: %s", err)
+			}
 		}
+		conditions.MarkTrue(cluster, infrastructurev1beta1.InfraClusterReachableCondition)
 	}
 
-	infraNamespace := cluster.Namespace
-	if cluster.Spec.ControlPlaneServiceTemplate.ObjectMeta.Namespace != "" {
-		infraNamespace = cluster.Spec.ControlPlaneServiceTemplate.ObjectMeta.Namespace
-	}
 	if !cluster.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(cluster, finalizer) {
-			var controlPlaneService corev1.Service
-			controlPlaneServiceKey := types.NamespacedName{
-				Name:      cluster.Name,
-				Namespace: infraNamespace,
-			}
-			controlPlaneServiceNotFound := false
-			if err := infraClusterClient.Get(ctx, controlPlaneServiceKey, &controlPlaneService); err != nil {
-				if apierrors.IsNotFound(err) {
-					controlPlaneServiceNotFound = true
-				} else {
-					return fmt.Errorf("get control plane Service: %s", err)
+			if cluster.Spec.ControlPlaneEndpointMode != infrastructurev1beta1.ControlPlaneEndpointModeExternal {
+				if err := r.deleteControlPlaneService(ctx, infraClusterClient, cluster, infraNamespace); err != nil {
+					return err
 				}
 			}
-
-			if !controlPlaneServiceNotFound {
-				if err := infraClusterClient.Delete(ctx, &controlPlaneService); err != nil {
-					return fmt.Errorf("delete control plane Service: %s", err)
-				}
-				r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "DeletedControlPlaneService", "Deleted control plane Service %q", controlPlaneService.Name)
+			if err := r.releaseControlPlaneEndpointAddress(ctx, cluster); err != nil {
+				return err
 			}
-
 			controllerutil.RemoveFinalizer(cluster, finalizer)
+			r.Tracker.ReleaseClients(cluster)
 		}
 	} else {
+		if cluster.Spec.ControlPlaneEndpointMode == "" {
+			// A ControlPlaneEndpoint set before the first reconciliation can only come from the user.
+			cluster.Spec.ControlPlaneEndpointMode = infrastructurev1beta1.ControlPlaneEndpointModeService
+			if !controllerutil.ContainsFinalizer(cluster, finalizer) && cluster.Spec.ControlPlaneEndpoint.IsValid() {
+				cluster.Spec.ControlPlaneEndpointMode = infrastructurev1beta1.ControlPlaneEndpointModeExternal
+			}
+		}
+
 		if !controllerutil.ContainsFinalizer(cluster, finalizer) {
 			controllerutil.AddFinalizer(cluster, finalizer)
 			return nil
 		}
 
-		ownerCluster, err := capiutil.GetOwnerCluster(ctx, r.Client, cluster.ObjectMeta)
+		failureDomainInfraClusterSecretRefs, err := r.reconcileFailureDomains(ctx, infraClusterClient, cluster)
 		if err != nil {
-			return fmt.Errorf("get owner Cluster: %s", err)
+			return err
 		}
+		// The clients of the infra clusters the VirtinkCluster used to be placed in are not needed anymore.
+		r.Tracker.ReleaseClients(cluster, append(failureDomainInfraClusterSecretRefs, infraClusterSecretRef)...)
+
+		// Nothing is provisioned until the VirtinkCluster is owned by a Cluster. A VirtinkCluster created from the
+		// VirtinkClusterTemplate of a ClusterClass is only owned by a shim object until the Cluster references it.
 		if ownerCluster == nil {
+			if cluster.Spec.ControlPlaneEndpointMode == infrastructurev1beta1.ControlPlaneEndpointModeExternal {
+				conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneEndpointReadyCondition, infrastructurev1beta1.WaitingForOwnerClusterReason, capiv1beta1.ConditionSeverityInfo, "")
+			} else {
+				conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.WaitingForOwnerClusterReason, capiv1beta1.ConditionSeverityInfo, "")
+			}
 			return nil
 		}
 
-		var controlPlaneService corev1.Service
-		controlPlaneServiceKey := types.NamespacedName{
-			Name:      cluster.Name,
-			Namespace: infraNamespace,
+		if cluster.Spec.ControlPlaneEndpointIPPoolRef != nil && cluster.Spec.ControlPlaneEndpointMode == infrastructurev1beta1.ControlPlaneEndpointModeService &&
+			controlPlaneServiceType(cluster) != corev1.ServiceTypeLoadBalancer {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.ControlPlaneServiceProvisioningFailedReason, capiv1beta1.ConditionSeverityError,
+				"ControlPlaneEndpointIPPoolRef requires a control plane Service of type LoadBalancer")
+			return nil
 		}
-		controlPlaneServiceNotFound := false
-		if err := infraClusterClient.Get(ctx, controlPlaneServiceKey, &controlPlaneService); err != nil {
-			if apierrors.IsNotFound(err) {
-				controlPlaneServiceNotFound = true
-			} else {
-				return fmt.Errorf("get control plane Service: %s", err)
-			}
+
+		controlPlaneEndpointIP, err := r.ensureControlPlaneEndpointAddress(ctx, cluster)
+		if err != nil {
+			return err
 		}
 
-		if controlPlaneServiceNotFound {
-			controlPlaneService, err := r.buildControlPlaneService(ctx, cluster, ownerCluster)
-			if err != nil {
-				return fmt.Errorf("build control plane Service: %s", err)
+		if cluster.Spec.ControlPlaneEndpointMode == infrastructurev1beta1.ControlPlaneEndpointModeExternal {
+			conditions.Delete(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition)
+			conditions.Delete(cluster, infrastructurev1beta1.ControlPlaneServiceSyncedCondition)
+			if controlPlaneEndpointIP != "" {
+				cluster.Spec.ControlPlaneEndpoint.Host = controlPlaneEndpointIP
+				if cluster.Spec.ControlPlaneEndpoint.Port == 0 {
+					cluster.Spec.ControlPlaneEndpoint.Port = defaultAPIServerPort
+				}
 			}
-
-			controlPlaneService.Name = controlPlaneServiceKey.Name
-			controlPlaneService.Namespace = controlPlaneServiceKey.Namespace
-			if err := infraClusterClient.Create(ctx, controlPlaneService); err != nil {
-				return fmt.Errorf("create control plane Service: %s", err)
+			if !cluster.Spec.ControlPlaneEndpoint.IsValid() {
+				conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneEndpointReadyCondition, infrastructurev1beta1.InvalidControlPlaneEndpointReason, capiv1beta1.ConditionSeverityError, "host and port of the ControlPlaneEndpoint must be set")
+				cluster.Status.Ready = false
+				return nil
 			}
-			r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "CreatedControlPlaneService", "Created control plane Service %q", controlPlaneService.Name)
+			conditions.MarkTrue(cluster, infrastructurev1beta1.ControlPlaneEndpointReadyCondition)
+			cluster.Status.Ready = true
+			return nil
+		}
+		conditions.Delete(cluster, infrastructurev1beta1.ControlPlaneEndpointReadyCondition)
+
+		controlPlaneServiceKey := types.NamespacedName{
+			Name:      cluster.Name,
+			Namespace: infraNamespace,
+		}
+		controlPlaneService, err := r.reconcileControlPlaneService(ctx, infraClusterClient, cluster, ownerCluster, controlPlaneServiceKey, controlPlaneEndpointIP)
+		if err != nil {
+			return err
 		}
 
-		if cluster.Spec.ControlPlaneServiceTemplate.Type != nil && *cluster.Spec.ControlPlaneServiceTemplate.Type == corev1.ServiceTypeLoadBalancer {
-			if len(controlPlaneService.Status.LoadBalancer.Ingress) == 0 {
+		switch {
+		case controlPlaneServiceType(cluster) == corev1.ServiceTypeLoadBalancer:
+			host := loadBalancerIngressHost(controlPlaneService)
+			if host == "" {
+				conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.WaitingForLoadBalancerReason, capiv1beta1.ConditionSeverityInfo, "")
 				return fmt.Errorf("control plane load balancer is not ready")
 			}
+			if controlPlaneEndpointIP != "" {
+				host = controlPlaneEndpointIP
+			}
 			cluster.Spec.ControlPlaneEndpoint = capiv1beta1.APIEndpoint{
-				Host: controlPlaneService.Status.LoadBalancer.Ingress[0].IP,
-				Port: 6443,
+				Host: host,
+				Port: controlPlaneServicePort(cluster),
 			}
-		} else {
+		case controlPlaneServiceType(cluster) == corev1.ServiceTypeNodePort && cluster.Spec.ControlPlaneServiceTemplate.NodePortPublishing != nil:
+			controlPlaneEndpoint, err := r.buildNodePortControlPlaneEndpoint(ctx, infraClusterClient, cluster, controlPlaneService)
+			if err != nil {
+				return err
+			}
+			cluster.Spec.ControlPlaneEndpoint = *controlPlaneEndpoint
+		default:
 			cluster.Spec.ControlPlaneEndpoint = capiv1beta1.APIEndpoint{
 				Host: controlPlaneService.Spec.ClusterIP,
-				Port: 6443,
+				Port: controlPlaneServicePort(cluster),
 			}
 		}
 
+		conditions.MarkTrue(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition)
 		cluster.Status.Ready = true
 	}
 
 	return nil
 }
 
-func buildInfraClusterClient(ctx context.Context, c client.Client, infraClusterSecretRef *corev1.ObjectReference) (client.Client, error) {
-	var infraClusterSecret corev1.Secret
-	infraClusterSecretKey := types.NamespacedName{
-		Name:      infraClusterSecretRef.Name,
-		Namespace: infraClusterSecretRef.Namespace,
+// ensureControlPlaneEndpointAddress claims an IP address from the ControlPlaneEndpointIPPoolRef, and returns the
+// allocated address, which is empty if no IP pool is referenced.
+func (r *VirtinkClusterReconciler) ensureControlPlaneEndpointAddress(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster) (string, error) {
+	if cluster.Spec.ControlPlaneEndpointIPPoolRef == nil {
+		conditions.Delete(cluster, infrastructurev1beta1.IPAddressAllocatedCondition)
+		return "", nil
 	}
-	if err := c.Get(ctx, infraClusterSecretKey, &infraClusterSecret); err != nil {
-		return nil, fmt.Errorf("get infra cluster kubeconfig Secret: %s", err)
+
+	ipClaimKey := controlPlaneEndpointIPClaimKey(cluster)
+	var ipClaim ipamv1.IPClaim
+	var ipClaimNotFound bool
+	if err := r.Get(ctx, ipClaimKey, &ipClaim); err != nil {
+		if !apierrors.IsNotFound(err) {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return "", fmt.Errorf("get IPClaim: %s", err)
+		}
+		ipClaimNotFound = true
 	}
 
-	kubeConfig, ok := infraClusterSecret.Data["kubeconfig"]
-	if !ok {
-		return nil, errors.New("retrieve infra kubeconfig from Secret: 'kubeconfig' key is missing")
+	if ipClaimNotFound {
+		ipClaim = ipamv1.IPClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       ipClaimKey.Name,
+				Namespace:  ipClaimKey.Namespace,
+				Finalizers: []string{finalizer},
+			},
+			Spec: ipamv1.IPClaimSpec{
+				Pool: corev1.ObjectReference{
+					Namespace: cluster.Namespace,
+					Name:      cluster.Spec.ControlPlaneEndpointIPPoolRef.Name,
+				},
+			},
+		}
+		if err := controllerutil.SetOwnerReference(cluster, &ipClaim, r.Scheme); err != nil {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return "", fmt.Errorf("set IPClaim owner: %s", err)
+		}
+		if err := r.Create(ctx, &ipClaim); err != nil {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return "", fmt.Errorf("create IPClaim: %s", err)
+		}
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "CreatedIPClaim", "Created IPClaim %q", ipClaim.Name)
 	}
 
-	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
-	if err != nil {
-		return nil, fmt.Errorf("create REST config: %s", err)
+	if ipClaim.Status.ErrorMessage != nil {
+		conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPAddressAllocationFailedReason, capiv1beta1.ConditionSeverityError, *ipClaim.Status.ErrorMessage)
+		return "", reconcileError{Result: ctrl.Result{Requeue: false}}
+	}
+
+	if ipClaim.Status.Address == nil {
+		conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.WaitingForIPAddressReason, capiv1beta1.ConditionSeverityInfo, "")
+		return "", reconcileError{Result: ctrl.Result{RequeueAfter: 1 * time.Second}}
 	}
 
-	infraClusterClient, err := client.New(restConfig, client.Options{Scheme: c.Scheme()})
+	var ipAddress ipamv1.IPAddress
+	ipAddressKey := types.NamespacedName{
+		Namespace: ipClaim.Status.Address.Namespace,
+		Name:      ipClaim.Status.Address.Name,
+	}
+	if err := r.Get(ctx, ipAddressKey, &ipAddress); err != nil {
+		conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return "", fmt.Errorf("get IPAddress: %s", err)
+	}
+
+	conditions.MarkTrue(cluster, infrastructurev1beta1.IPAddressAllocatedCondition)
+	return string(ipAddress.Spec.Address), nil
+}
+
+// releaseControlPlaneEndpointAddress removes the finalizer of the IPClaim of the ControlPlaneEndpoint, so that the
+// IPClaim is garbage collected with the cluster.
+func (r *VirtinkClusterReconciler) releaseControlPlaneEndpointAddress(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster) error {
+	if cluster.Spec.ControlPlaneEndpointIPPoolRef == nil {
+		return nil
+	}
+
+	var ipClaim ipamv1.IPClaim
+	if err := r.Get(ctx, controlPlaneEndpointIPClaimKey(cluster), &ipClaim); err != nil {
+		if apierrors.IsNotFound(err) {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, capiv1beta1.DeletedReason, capiv1beta1.ConditionSeverityInfo, "")
+			return nil
+		}
+		conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, capiv1beta1.DeletionFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return fmt.Errorf("get IPClaim: %s", err)
+	}
+
+	if controllerutil.ContainsFinalizer(&ipClaim, finalizer) {
+		controllerutil.RemoveFinalizer(&ipClaim, finalizer)
+		if err := r.Update(ctx, &ipClaim); err != nil {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, capiv1beta1.DeletionFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return fmt.Errorf("update IPClaim: %s", err)
+		}
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "ReleasedIPClaim", "Released IPClaim %q", ipClaim.Name)
+	}
+	conditions.MarkFalse(cluster, infrastructurev1beta1.IPAddressAllocatedCondition, capiv1beta1.DeletedReason, capiv1beta1.ConditionSeverityInfo, "")
+	return nil
+}
+
+func controlPlaneEndpointIPClaimKey(cluster *infrastructurev1beta1.VirtinkCluster) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      cluster.Name + "-control-plane-endpoint",
+		Namespace: cluster.Namespace,
+	}
+}
+
+// deleteControlPlaneService deletes the control plane Service, unless it was not created for the cluster.
+func (r *VirtinkClusterReconciler) deleteControlPlaneService(ctx context.Context, infraClusterClient client.Client, cluster *infrastructurev1beta1.VirtinkCluster, infraNamespace string) error {
+	var controlPlaneService corev1.Service
+	controlPlaneServiceKey := types.NamespacedName{
+		Name:      cluster.Name,
+		Namespace: infraNamespace,
+	}
+	conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, capiv1beta1.DeletingReason, capiv1beta1.ConditionSeverityInfo, "")
+
+	if err := infraClusterClient.Get(ctx, controlPlaneServiceKey, &controlPlaneService); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("get control plane Service: %s", err)
+	}
+
+	labels := controlPlaneService.Labels
+	if labels[virtinkClusterNameLabel] != cluster.Name || labels[virtinkClusterNamespaceLabel] != cluster.Namespace {
+		ctrl.LoggerFrom(ctx).Info("skip deleting Service not created for the cluster", "service", controlPlaneServiceKey)
+		return nil
+	}
+
+	if controlPlaneService.DeletionTimestamp.IsZero() {
+		if err := infraClusterClient.Delete(ctx, &controlPlaneService); err != nil {
+			return fmt.Errorf("delete control plane Service: %s", err)
+		}
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "DeletedControlPlaneService", "Deleted control plane Service %q", controlPlaneService.Name)
+	}
+
+	// The control plane endpoint address is only released once the Service and its load balancer are gone, so that
+	// the address is not allocated again while it is still in use.
+	conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, capiv1beta1.DeletingReason, capiv1beta1.ConditionSeverityInfo, "waiting for control plane Service to be deleted")
+	return reconcileError{Result: ctrl.Result{RequeueAfter: 3 * time.Second}}
+}
+
+// reconcileControlPlaneService applies the control plane Service built from the ControlPlaneServiceTemplate with
+// server-side apply, so that changes to the template roll out and hand edits of the owned fields are reverted.
+func (r *VirtinkClusterReconciler) reconcileControlPlaneService(ctx context.Context, infraClusterClient client.Client, cluster *infrastructurev1beta1.VirtinkCluster, ownerCluster *capiv1beta1.Cluster, controlPlaneServiceKey types.NamespacedName, loadBalancerIP string) (*corev1.Service, error) {
+	desiredService, err := r.buildControlPlaneService(ctx, cluster, ownerCluster, loadBalancerIP)
 	if err != nil {
-		return nil, fmt.Errorf("create infra cluster client: %s", err)
+		conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.ControlPlaneServiceProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return nil, fmt.Errorf("build control plane Service: %s", err)
+	}
+	desiredService.Name = controlPlaneServiceKey.Name
+	desiredService.Namespace = controlPlaneServiceKey.Namespace
+
+	var liveService corev1.Service
+	liveServiceNotFound := false
+	if err := infraClusterClient.Get(ctx, controlPlaneServiceKey, &liveService); err != nil {
+		if !apierrors.IsNotFound(err) {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.ControlPlaneServiceProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, fmt.Errorf("get control plane Service: %s", err)
+		}
+		liveServiceNotFound = true
 	}
-	return infraClusterClient, nil
+
+	var drift []string
+	if !liveServiceNotFound {
+		drift = diffControlPlaneService(desiredService, &liveService)
+	}
+
+	appliedService := desiredService.DeepCopy()
+	if err := infraClusterClient.Patch(ctx, appliedService, client.Apply, client.FieldOwner(controlPlaneServiceFieldManager), client.ForceOwnership); err != nil {
+		if len(drift) > 0 {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceSyncedCondition, infrastructurev1beta1.ControlPlaneServiceDriftedReason, capiv1beta1.ConditionSeverityWarning,
+				"control plane Service diverges from template in %s", strings.Join(drift, ", "))
+		}
+		conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.ControlPlaneServiceProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return nil, fmt.Errorf("apply control plane Service: %s", err)
+	}
+
+	if liveServiceNotFound {
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "CreatedControlPlaneService", "Created control plane Service %q", appliedService.Name)
+	} else if len(drift) > 0 {
+		r.Recorder.Eventf(cluster, corev1.EventTypeNormal, "UpdatedControlPlaneService", "Updated %s of control plane Service %q to match template", strings.Join(drift, ", "), appliedService.Name)
+	}
+
+	// Another field manager, e.g. a mutating webhook of the infra cluster, may still override the applied fields.
+	if drift := diffControlPlaneService(desiredService, appliedService); len(drift) > 0 {
+		conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceSyncedCondition, infrastructurev1beta1.ControlPlaneServiceDriftedReason, capiv1beta1.ConditionSeverityWarning,
+			"control plane Service diverges from template in %s", strings.Join(drift, ", "))
+	} else {
+		conditions.MarkTrue(cluster, infrastructurev1beta1.ControlPlaneServiceSyncedCondition)
+	}
+	return appliedService, nil
 }
 
-func (r *VirtinkClusterReconciler) buildControlPlaneService(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster, ownerCluster *capiv1beta1.Cluster) (*corev1.Service, error) {
+// diffControlPlaneService returns the fields of the live control plane Service that diverge from the desired one.
+// Labels and annotations added by others are tolerated.
+func diffControlPlaneService(desired *corev1.Service, live *corev1.Service) []string {
+	var drift []string
+	if desired.Spec.Type != live.Spec.Type {
+		drift = append(drift, "type")
+	}
+	if !isSubset(desired.Labels, live.Labels) {
+		drift = append(drift, "labels")
+	}
+	if !isSubset(desired.Annotations, live.Annotations) {
+		drift = append(drift, "annotations")
+	}
+	if desired.Spec.LoadBalancerIP != live.Spec.LoadBalancerIP {
+		drift = append(drift, "loadBalancerIP")
+	}
+	if !reflect.DeepEqual(desired.Spec.Selector, live.Spec.Selector) {
+		drift = append(drift, "selector")
+	}
+	portsDrifted := len(desired.Spec.Ports) != len(live.Spec.Ports)
+	for i := 0; !portsDrifted && i < len(desired.Spec.Ports); i++ {
+		portsDrifted = desired.Spec.Ports[i].Port != live.Spec.Ports[i].Port ||
+			desired.Spec.Ports[i].TargetPort != live.Spec.Ports[i].TargetPort
+	}
+	if portsDrifted {
+		drift = append(drift, "ports")
+	}
+	return drift
+}
+
+func isSubset(subset map[string]string, set map[string]string) bool {
+	for key, value := range subset {
+		if v, ok := set[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *VirtinkClusterReconciler) buildControlPlaneService(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster, ownerCluster *capiv1beta1.Cluster, loadBalancerIP string) (*corev1.Service, error) {
 	service := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
 		Spec: corev1.ServiceSpec{
+			Type: controlPlaneServiceType(cluster),
 			Selector: map[string]string{
 				capiv1beta1.ClusterLabelName:             ownerCluster.Name,
 				capiv1beta1.MachineControlPlaneLabelName: "",
 			},
 			Ports: []corev1.ServicePort{{
-				Port:       6443,
-				TargetPort: intstr.FromInt(6443),
+				Port:       controlPlaneServicePort(cluster),
+				TargetPort: intstr.FromInt(int(controlPlaneServiceTargetPort(cluster))),
 			}},
 		},
 	}
-	service.Labels = cluster.Spec.ControlPlaneServiceTemplate.ObjectMeta.Labels
-	service.Annotations = cluster.Spec.ControlPlaneServiceTemplate.ObjectMeta.Annotations
-	if cluster.Spec.ControlPlaneServiceTemplate.Type != nil {
-		service.Spec.Type = *cluster.Spec.ControlPlaneServiceTemplate.Type
+	for name, value := range cluster.Spec.ControlPlaneServiceTemplate.ObjectMeta.Labels {
+		service.Labels[name] = value
+	}
+	for name, value := range cluster.Spec.ControlPlaneServiceTemplate.ObjectMeta.Annotations {
+		service.Annotations[name] = value
+	}
+	service.Labels[virtinkClusterNameLabel] = cluster.Name
+	service.Labels[virtinkClusterNamespaceLabel] = cluster.Namespace
+	if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		service.Spec.LoadBalancerIP = loadBalancerIP
 	}
 	return service, nil
 }
 
+// buildNodePortControlPlaneEndpoint builds the ControlPlaneEndpoint from the node port of the control plane Service
+// and a host configured in NodePortPublishing, or an address of a ready infra cluster node.
+func (r *VirtinkClusterReconciler) buildNodePortControlPlaneEndpoint(ctx context.Context, infraClusterClient client.Client, cluster *infrastructurev1beta1.VirtinkCluster, controlPlaneService *corev1.Service) (*capiv1beta1.APIEndpoint, error) {
+	var nodePort int32
+	for _, port := range controlPlaneService.Spec.Ports {
+		if port.Port == controlPlaneServicePort(cluster) {
+			nodePort = port.NodePort
+		}
+	}
+	if nodePort == 0 {
+		conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.WaitingForNodePortReason, capiv1beta1.ConditionSeverityInfo, "node port is not allocated")
+		return nil, fmt.Errorf("control plane node port is not allocated")
+	}
+
+	publishing := cluster.Spec.ControlPlaneServiceTemplate.NodePortPublishing
+	hosts := publishing.Hosts
+	if len(hosts) == 0 {
+		addressType := publishing.AddressType
+		if addressType == "" {
+			addressType = corev1.NodeInternalIP
+		}
+
+		var nodeList corev1.NodeList
+		if err := infraClusterClient.List(ctx, &nodeList); err != nil {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.ControlPlaneServiceProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, fmt.Errorf("list infra cluster Nodes: %s", err)
+		}
+		hosts = readyNodeAddresses(nodeList.Items, addressType)
+		if len(hosts) == 0 {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition, infrastructurev1beta1.WaitingForNodePortReason, capiv1beta1.ConditionSeverityInfo, "no ready infra cluster Node has a %s address", addressType)
+			return nil, fmt.Errorf("no ready infra cluster Node has a %s address", addressType)
+		}
+	}
+
+	// Keep the published host as long as possible, since it is baked into the kubeconfig and certificates of the
+	// workload cluster.
+	host := hosts[0]
+	for _, h := range hosts {
+		if h == cluster.Spec.ControlPlaneEndpoint.Host {
+			host = h
+			break
+		}
+	}
+	return &capiv1beta1.APIEndpoint{
+		Host: host,
+		Port: nodePort,
+	}, nil
+}
+
+// reconcileFailureDomains publishes the FailureDomains of the VirtinkCluster in status, or the failure domains
+// discovered from the FailureDomainTopologyKey label of the infra cluster Nodes if none is declared. Failure domains
+// whose own infra cluster can not be reached are left out, so that no new machine is placed in them. The references
+// to the infra cluster kubeconfig Secrets of the failure domains are returned.
+func (r *VirtinkClusterReconciler) reconcileFailureDomains(ctx context.Context, infraClusterClient client.Client, cluster *infrastructurev1beta1.VirtinkCluster) ([]*corev1.ObjectReference, error) {
+	if len(cluster.Spec.FailureDomains) > 0 {
+		failureDomains := capiv1beta1.FailureDomains{}
+		var infraClusterSecretRefs []*corev1.ObjectReference
+		var errs []error
+		for i := range cluster.Spec.FailureDomains {
+			failureDomain := &cluster.Spec.FailureDomains[i]
+			if failureDomain.IdentityRef != nil {
+				infraClusterSecretRef, err := r.checkFailureDomainInfraCluster(ctx, cluster, failureDomain)
+				if infraClusterSecretRef != nil {
+					infraClusterSecretRefs = append(infraClusterSecretRefs, infraClusterSecretRef)
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failure domain %q: %w", failureDomain.Name, err))
+					continue
+				}
+			}
+			failureDomains[failureDomain.Name] = capiv1beta1.FailureDomainSpec{
+				ControlPlane: failureDomain.ControlPlane,
+			}
+		}
+		cluster.Status.FailureDomains = failureDomains
+		if len(errs) > 0 {
+			conditions.MarkFalse(cluster, infrastructurev1beta1.FailureDomainsReachableCondition, infrastructurev1beta1.FailureDomainInfraClusterUnreachableReason, capiv1beta1.ConditionSeverityWarning, kerrors.NewAggregate(errs).Error())
+		} else {
+			conditions.MarkTrue(cluster, infrastructurev1beta1.FailureDomainsReachableCondition)
+		}
+		return infraClusterSecretRefs, nil
+	}
+
+	conditions.Delete(cluster, infrastructurev1beta1.FailureDomainsReachableCondition)
+	if cluster.Spec.FailureDomainTopologyKey == "" {
+		cluster.Status.FailureDomains = nil
+		return nil, nil
+	}
+
+	var nodeList corev1.NodeList
+	if err := infraClusterClient.List(ctx, &nodeList, client.HasLabels{cluster.Spec.FailureDomainTopologyKey}); err != nil {
+		return nil, fmt.Errorf("list infra cluster Nodes: %s", err)
+	}
+	failureDomains := capiv1beta1.FailureDomains{}
+	for _, node := range nodeList.Items {
+		if name := node.Labels[cluster.Spec.FailureDomainTopologyKey]; name != "" {
+			failureDomains[name] = capiv1beta1.FailureDomainSpec{
+				ControlPlane: true,
+			}
+		}
+	}
+	cluster.Status.FailureDomains = failureDomains
+	return nil, nil
+}
+
+func (r *VirtinkClusterReconciler) checkFailureDomainInfraCluster(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster, failureDomain *infrastructurev1beta1.FailureDomain) (*corev1.ObjectReference, error) {
+	infraClusterSecretRef, err := getFailureDomainInfraClusterSecretRef(ctx, r.Client, cluster, failureDomain, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Tracker.GetClient(ctx, cluster, infraClusterSecretRef, failureDomain.Namespace); err != nil {
+		return infraClusterSecretRef, fmt.Errorf("build infra cluster client: %s", err)
+	}
+	return infraClusterSecretRef, nil
+}
+
+// failureDomainTopologyKey returns the label key of the infra cluster Nodes that identifies their failure domain.
+func failureDomainTopologyKey(cluster *infrastructurev1beta1.VirtinkCluster) string {
+	if cluster.Spec.FailureDomainTopologyKey != "" {
+		return cluster.Spec.FailureDomainTopologyKey
+	}
+	return corev1.LabelTopologyZone
+}
+
+// readyNodeAddresses returns the addresses of the given type of the ready Nodes, sorted by Node name.
+func readyNodeAddresses(nodes []corev1.Node, addressType corev1.NodeAddressType) []string {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	var addresses []string
+	for _, node := range nodes {
+		ready := false
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+		if !ready {
+			continue
+		}
+		for _, address := range node.Status.Addresses {
+			if address.Type == addressType {
+				addresses = append(addresses, address.Address)
+			}
+		}
+	}
+	return addresses
+}
+
+func controlPlaneServiceType(cluster *infrastructurev1beta1.VirtinkCluster) corev1.ServiceType {
+	if cluster.Spec.ControlPlaneServiceTemplate.Type != nil {
+		return *cluster.Spec.ControlPlaneServiceTemplate.Type
+	}
+	return corev1.ServiceTypeClusterIP
+}
+
+func controlPlaneServicePort(cluster *infrastructurev1beta1.VirtinkCluster) int32 {
+	if cluster.Spec.ControlPlaneServiceTemplate.Port != nil {
+		return *cluster.Spec.ControlPlaneServiceTemplate.Port
+	}
+	return defaultAPIServerPort
+}
+
+func controlPlaneServiceTargetPort(cluster *infrastructurev1beta1.VirtinkCluster) int32 {
+	if cluster.Spec.ControlPlaneServiceTemplate.TargetPort != nil {
+		return *cluster.Spec.ControlPlaneServiceTemplate.TargetPort
+	}
+	return defaultAPIServerPort
+}
+
+// loadBalancerIngressHost returns the first IP or hostname published by the load balancer of the Service, which is
+// empty until the load balancer is ready.
+func loadBalancerIngressHost(service *corev1.Service) string {
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return ingress.IP
+		}
+		if ingress.Hostname != "" {
+			return ingress.Hostname
+		}
+	}
+	return ""
+}
+
 // SetupWithManager sets up the controller with the Manager.
-func (r *VirtinkClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&infrastructurev1beta1.VirtinkCluster{}).
-		Complete(r)
+func (r *VirtinkClusterReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	log := ctrl.LoggerFrom(ctx)
+	gvk, err := apiutil.GVKForObject(&infrastructurev1beta1.VirtinkCluster{}, mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		For(&infrastructurev1beta1.VirtinkCluster{}, builder.WithPredicates(predicates.ResourceNotPausedAndHasFilterLabel(log, r.WatchFilterValue))).
+		Watches(
+			&source.Kind{Type: &capiv1beta1.Cluster{}},
+			handler.EnqueueRequestsFromMapFunc(capiutil.ClusterToInfrastructureMapFunc(ctx, gvk, mgr.GetClient(), &infrastructurev1beta1.VirtinkCluster{})),
+			builder.WithPredicates(predicates.ClusterUnpaused(log)),
+		).
+		Watches(&source.Kind{Type: &corev1.Service{}}, handler.EnqueueRequestsFromMapFunc(infraObjectToVirtinkCluster)).
+		Watches(&source.Kind{Type: &infrastructurev1beta1.VirtinkClusterIdentity{}}, handler.EnqueueRequestsFromMapFunc(r.identityToVirtinkClusters)).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	r.controller = c
+	return nil
+}
+
+func (r *VirtinkClusterReconciler) identityToVirtinkClusters(obj client.Object) []ctrl.Request {
+	var clusterList infrastructurev1beta1.VirtinkClusterList
+	if err := r.List(context.Background(), &clusterList); err != nil {
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for _, cluster := range clusterList.Items {
+		if cluster.Spec.IdentityRef != nil && cluster.Spec.IdentityRef.Name == obj.GetName() {
+			requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}})
+		}
+	}
+	return requests
+}
+
+func infraObjectToVirtinkCluster(obj client.Object) []ctrl.Request {
+	labels := obj.GetLabels()
+	name, namespace := labels[virtinkClusterNameLabel], labels[virtinkClusterNamespaceLabel]
+	if name == "" || namespace == "" {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
 }
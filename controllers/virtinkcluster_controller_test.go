@@ -2,13 +2,18 @@ package controllers
 
 import (
 	"github.com/google/uuid"
+	ipamv1 "github.com/metal3-io/ip-address-manager/api/v1alpha1"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	infrastructurev1beta1 "github.com/smartxworks/cluster-api-provider-virtink/api/v1beta1"
@@ -51,6 +56,61 @@ var _ = Describe("VirtinkCluster controller", func() {
 			}).Should(BeTrue())
 		})
 
+		It("should publish the declared failure domains", func() {
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Eventually(func() error {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				virtinkCluster.Spec.FailureDomains = []infrastructurev1beta1.FailureDomain{
+					{Name: "zone-a", ControlPlane: true},
+					{Name: "zone-b"},
+				}
+				return k8sClient.Update(ctx, &virtinkCluster)
+			}).Should(Succeed())
+
+			Eventually(func() capiv1beta1.FailureDomains {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return virtinkCluster.Status.FailureDomains
+			}).Should(Equal(capiv1beta1.FailureDomains{
+				"zone-a": capiv1beta1.FailureDomainSpec{ControlPlane: true},
+				"zone-b": capiv1beta1.FailureDomainSpec{},
+			}))
+		})
+
+		It("should leave out failure domains with unreachable infra clusters", func() {
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Eventually(func() error {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				virtinkCluster.Spec.FailureDomains = []infrastructurev1beta1.FailureDomain{
+					{Name: "room-a", ControlPlane: true},
+					{Name: "room-b", ControlPlane: true, IdentityRef: &infrastructurev1beta1.VirtinkClusterIdentityReference{Name: "identity-" + uuid.New().String()}},
+				}
+				return k8sClient.Update(ctx, &virtinkCluster)
+			}).Should(Succeed())
+
+			Eventually(func() bool {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return conditions.IsFalse(&virtinkCluster, infrastructurev1beta1.FailureDomainsReachableCondition)
+			}).Should(BeTrue())
+			Expect(conditions.GetReason(&virtinkCluster, infrastructurev1beta1.FailureDomainsReachableCondition)).To(Equal(infrastructurev1beta1.FailureDomainInfraClusterUnreachableReason))
+			Expect(conditions.IsTrue(&virtinkCluster, infrastructurev1beta1.InfraClusterReachableCondition)).To(BeTrue())
+			Expect(virtinkCluster.Status.FailureDomains).To(Equal(capiv1beta1.FailureDomains{
+				"room-a": capiv1beta1.FailureDomainSpec{ControlPlane: true},
+			}))
+		})
+
+		It("should not create control plane service until owner cluster is set", func() {
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Eventually(func() string {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return conditions.GetReason(&virtinkCluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition)
+			}).Should(Equal(infrastructurev1beta1.WaitingForOwnerClusterReason))
+
+			var svc corev1.Service
+			Consistently(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkClusterKey, &svc))
+			}).Should(BeTrue())
+		})
+
 		Context("when owner cluster is set", func() {
 			BeforeEach(func() {
 				var cluster capiv1beta1.Cluster
@@ -76,6 +136,483 @@ var _ = Describe("VirtinkCluster controller", func() {
 					return k8sClient.Get(ctx, svcKey, &svc)
 				}).Should(Succeed())
 			})
+
+			It("should mark control plane service as ready", func() {
+				var virtinkCluster infrastructurev1beta1.VirtinkCluster
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+					return conditions.IsTrue(&virtinkCluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition)
+				}).Should(BeTrue())
+				Expect(conditions.IsTrue(&virtinkCluster, capiv1beta1.ReadyCondition)).To(BeTrue())
+			})
+
+			It("should use the configured control plane service ports", func() {
+				var virtinkCluster infrastructurev1beta1.VirtinkCluster
+				Eventually(func() error {
+					Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+					port, targetPort := int32(7443), int32(8443)
+					virtinkCluster.Spec.ControlPlaneServiceTemplate.Port = &port
+					virtinkCluster.Spec.ControlPlaneServiceTemplate.TargetPort = &targetPort
+					return k8sClient.Update(ctx, &virtinkCluster)
+				}).Should(Succeed())
+
+				var svcKey = types.NamespacedName{Namespace: virtinkClusterKey.Namespace, Name: virtinkClusterKey.Name}
+				var svc corev1.Service
+				Eventually(func() []corev1.ServicePort {
+					Expect(client.IgnoreNotFound(k8sClient.Get(ctx, svcKey, &svc))).To(Succeed())
+					return svc.Spec.Ports
+				}).Should(ConsistOf(And(
+					HaveField("Port", int32(7443)),
+					HaveField("TargetPort", intstr.FromInt(8443)),
+				)))
+
+				Eventually(func() int32 {
+					Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+					return virtinkCluster.Spec.ControlPlaneEndpoint.Port
+				}).Should(Equal(int32(7443)))
+			})
+
+			It("should publish node port with the configured hosts", func() {
+				var virtinkCluster infrastructurev1beta1.VirtinkCluster
+				Eventually(func() error {
+					Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+					serviceType := corev1.ServiceTypeNodePort
+					virtinkCluster.Spec.ControlPlaneServiceTemplate.Type = &serviceType
+					virtinkCluster.Spec.ControlPlaneServiceTemplate.NodePortPublishing = &infrastructurev1beta1.NodePortPublishing{
+						Hosts: []string{"192.0.2.10"},
+					}
+					return k8sClient.Update(ctx, &virtinkCluster)
+				}).Should(Succeed())
+
+				var svcKey = types.NamespacedName{Namespace: virtinkClusterKey.Namespace, Name: virtinkClusterKey.Name}
+				var svc corev1.Service
+				Eventually(func() corev1.ServiceType {
+					Expect(client.IgnoreNotFound(k8sClient.Get(ctx, svcKey, &svc))).To(Succeed())
+					return svc.Spec.Type
+				}).Should(Equal(corev1.ServiceTypeNodePort))
+
+				Eventually(func() capiv1beta1.APIEndpoint {
+					Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+					return virtinkCluster.Spec.ControlPlaneEndpoint
+				}).Should(Equal(capiv1beta1.APIEndpoint{Host: "192.0.2.10", Port: svc.Spec.Ports[0].NodePort}))
+			})
+
+			It("should revert hand edits of control plane service", func() {
+				var svcKey = types.NamespacedName{Namespace: virtinkClusterKey.Namespace, Name: virtinkClusterKey.Name}
+				var svc corev1.Service
+				Eventually(func() error {
+					return k8sClient.Get(ctx, svcKey, &svc)
+				}).Should(Succeed())
+
+				Eventually(func() error {
+					Expect(k8sClient.Get(ctx, svcKey, &svc)).To(Succeed())
+					svc.Spec.Type = corev1.ServiceTypeNodePort
+					return k8sClient.Update(ctx, &svc)
+				}).Should(Succeed())
+
+				Eventually(func() corev1.ServiceType {
+					Expect(k8sClient.Get(ctx, svcKey, &svc)).To(Succeed())
+					return svc.Spec.Type
+				}).Should(Equal(corev1.ServiceTypeClusterIP))
+
+				var virtinkCluster infrastructurev1beta1.VirtinkCluster
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+					return conditions.IsTrue(&virtinkCluster, infrastructurev1beta1.ControlPlaneServiceSyncedCondition)
+				}).Should(BeTrue())
+			})
+		})
+	})
+
+	Context("for a VirtinkCluster with an external control plane endpoint", func() {
+		var virtinkClusterKey types.NamespacedName
+		BeforeEach(func() {
+			By("creating a new VirtinkCluster with control plane endpoint")
+			virtinkClusterKey = types.NamespacedName{
+				Name:      "cluster-" + uuid.New().String(),
+				Namespace: "default",
+			}
+
+			cluster := capiv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      virtinkClusterKey.Name,
+					Namespace: virtinkClusterKey.Namespace,
+				},
+				Spec: capiv1beta1.ClusterSpec{},
+			}
+			Expect(k8sClient.Create(ctx, &cluster)).To(Succeed())
+
+			virtinkCluster := infrastructurev1beta1.VirtinkCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      virtinkClusterKey.Name,
+					Namespace: virtinkClusterKey.Namespace,
+				},
+				Spec: infrastructurev1beta1.VirtinkClusterSpec{
+					ControlPlaneEndpoint: capiv1beta1.APIEndpoint{
+						Host: "192.0.2.100",
+						Port: 6443,
+					},
+				},
+			}
+			Expect(controllerutil.SetOwnerReference(&cluster, &virtinkCluster, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, &virtinkCluster)).To(Succeed())
+		})
+
+		It("should be ready without control plane service", func() {
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Eventually(func() bool {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return virtinkCluster.Status.Ready
+			}).Should(BeTrue())
+			Expect(virtinkCluster.Spec.ControlPlaneEndpointMode).To(Equal(infrastructurev1beta1.ControlPlaneEndpointModeExternal))
+			Expect(virtinkCluster.Spec.ControlPlaneEndpoint.Host).To(Equal("192.0.2.100"))
+			Expect(conditions.IsTrue(&virtinkCluster, infrastructurev1beta1.ControlPlaneEndpointReadyCondition)).To(BeTrue())
+
+			var svcKey = types.NamespacedName{Namespace: virtinkClusterKey.Namespace, Name: virtinkClusterKey.Name}
+			var svc corev1.Service
+			Consistently(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, svcKey, &svc))
+			}).Should(BeTrue())
+		})
+	})
+
+	Context("for a VirtinkCluster with a control plane endpoint IP pool", func() {
+		var virtinkClusterKey types.NamespacedName
+		var ipClaimKey types.NamespacedName
+		var serviceType corev1.ServiceType
+		var controlPlaneEndpointMode infrastructurev1beta1.ControlPlaneEndpointMode
+		BeforeEach(func() {
+			serviceType = corev1.ServiceTypeLoadBalancer
+			controlPlaneEndpointMode = infrastructurev1beta1.ControlPlaneEndpointModeService
+		})
+
+		JustBeforeEach(func() {
+			By("creating a new VirtinkCluster with control plane endpoint IP pool")
+			virtinkClusterKey = types.NamespacedName{
+				Name:      "cluster-" + uuid.New().String(),
+				Namespace: "default",
+			}
+			ipClaimKey = types.NamespacedName{
+				Name:      virtinkClusterKey.Name + "-control-plane-endpoint",
+				Namespace: virtinkClusterKey.Namespace,
+			}
+
+			cluster := capiv1beta1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      virtinkClusterKey.Name,
+					Namespace: virtinkClusterKey.Namespace,
+				},
+				Spec: capiv1beta1.ClusterSpec{},
+			}
+			Expect(k8sClient.Create(ctx, &cluster)).To(Succeed())
+
+			virtinkCluster := infrastructurev1beta1.VirtinkCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      virtinkClusterKey.Name,
+					Namespace: virtinkClusterKey.Namespace,
+				},
+				Spec: infrastructurev1beta1.VirtinkClusterSpec{
+					ControlPlaneEndpointMode: controlPlaneEndpointMode,
+					ControlPlaneEndpointIPPoolRef: &corev1.TypedLocalObjectReference{
+						APIGroup: pointer.String(ipamv1.GroupVersion.Group),
+						Kind:     "IPPool",
+						Name:     "pool",
+					},
+					ControlPlaneServiceTemplate: infrastructurev1beta1.ControlPlaneServiceTemplate{
+						Type: &serviceType,
+					},
+				},
+			}
+			Expect(controllerutil.SetOwnerReference(&cluster, &virtinkCluster, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, &virtinkCluster)).To(Succeed())
+		})
+
+		allocateIPAddress := func(address string) {
+			var ipClaim ipamv1.IPClaim
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ipClaimKey, &ipClaim)
+			}).Should(Succeed())
+
+			ipAddress := ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ipClaim.Name,
+					Namespace: ipClaim.Namespace,
+				},
+				Spec: ipamv1.IPAddressSpec{
+					Claim:   corev1.ObjectReference{Name: ipClaim.Name, Namespace: ipClaim.Namespace},
+					Pool:    ipClaim.Spec.Pool,
+					Address: ipamv1.IPAddressStr(address),
+					Prefix:  24,
+				},
+			}
+			Expect(k8sClient.Create(ctx, &ipAddress)).To(Succeed())
+			Eventually(func() error {
+				Expect(k8sClient.Get(ctx, ipClaimKey, &ipClaim)).To(Succeed())
+				ipClaim.Status.Address = &corev1.ObjectReference{Name: ipAddress.Name, Namespace: ipAddress.Namespace}
+				return k8sClient.Status().Update(ctx, &ipClaim)
+			}).Should(Succeed())
+		}
+
+		It("should claim the control plane endpoint address for the load balancer", func() {
+			var ipClaim ipamv1.IPClaim
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ipClaimKey, &ipClaim)
+			}).Should(Succeed())
+			Expect(ipClaim.Spec.Pool.Name).To(Equal("pool"))
+			Expect(controllerutil.ContainsFinalizer(&ipClaim, finalizer)).To(BeTrue())
+
+			allocateIPAddress("192.0.2.200")
+
+			var svcKey = types.NamespacedName{Namespace: virtinkClusterKey.Namespace, Name: virtinkClusterKey.Name}
+			var svc corev1.Service
+			Eventually(func() string {
+				Expect(client.IgnoreNotFound(k8sClient.Get(ctx, svcKey, &svc))).To(Succeed())
+				return svc.Spec.LoadBalancerIP
+			}).Should(Equal("192.0.2.200"))
+
+			By("publishing the load balancer ingress")
+			svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "192.0.2.200"}}
+			Expect(k8sClient.Status().Update(ctx, &svc)).To(Succeed())
+
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Eventually(func() capiv1beta1.APIEndpoint {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return virtinkCluster.Spec.ControlPlaneEndpoint
+			}).Should(Equal(capiv1beta1.APIEndpoint{Host: "192.0.2.200", Port: 6443}))
+			Expect(conditions.IsTrue(&virtinkCluster, infrastructurev1beta1.IPAddressAllocatedCondition)).To(BeTrue())
+		})
+
+		It("should release the claim when deleted", func() {
+			var ipClaim ipamv1.IPClaim
+			Eventually(func() error {
+				return k8sClient.Get(ctx, ipClaimKey, &ipClaim)
+			}).Should(Succeed())
+
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &virtinkCluster)).To(Succeed())
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster))
+			}).Should(BeTrue())
+
+			// The IPClaim is left to the garbage collector, which does not run in envtest.
+			Expect(k8sClient.Get(ctx, ipClaimKey, &ipClaim)).To(Succeed())
+			Expect(controllerutil.ContainsFinalizer(&ipClaim, finalizer)).To(BeFalse())
+		})
+
+		It("should release the claim only after the control plane Service is deleted", func() {
+			allocateIPAddress("192.0.2.200")
+
+			var svcKey = types.NamespacedName{Namespace: virtinkClusterKey.Namespace, Name: virtinkClusterKey.Name}
+			var svc corev1.Service
+			Eventually(func() error {
+				return k8sClient.Get(ctx, svcKey, &svc)
+			}).Should(Succeed())
+			svc.Finalizers = append(svc.Finalizers, "test.virtink.smartx.com/load-balancer")
+			Expect(k8sClient.Update(ctx, &svc)).To(Succeed())
+
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &virtinkCluster)).To(Succeed())
+
+			var ipClaim ipamv1.IPClaim
+			Consistently(func() bool {
+				Expect(k8sClient.Get(ctx, ipClaimKey, &ipClaim)).To(Succeed())
+				return controllerutil.ContainsFinalizer(&ipClaim, finalizer)
+			}).Should(BeTrue())
+
+			By("deleting the control plane Service")
+			Eventually(func() error {
+				Expect(k8sClient.Get(ctx, svcKey, &svc)).To(Succeed())
+				svc.Finalizers = nil
+				return k8sClient.Update(ctx, &svc)
+			}).Should(Succeed())
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster))
+			}).Should(BeTrue())
+			Expect(k8sClient.Get(ctx, ipClaimKey, &ipClaim)).To(Succeed())
+			Expect(controllerutil.ContainsFinalizer(&ipClaim, finalizer)).To(BeFalse())
+		})
+
+		Context("when the control plane endpoint mode is External", func() {
+			BeforeEach(func() {
+				controlPlaneEndpointMode = infrastructurev1beta1.ControlPlaneEndpointModeExternal
+			})
+
+			It("should publish the allocated address as the control plane endpoint", func() {
+				allocateIPAddress("192.0.2.201")
+
+				var virtinkCluster infrastructurev1beta1.VirtinkCluster
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+					return virtinkCluster.Status.Ready
+				}).Should(BeTrue())
+				Expect(virtinkCluster.Spec.ControlPlaneEndpoint).To(Equal(capiv1beta1.APIEndpoint{Host: "192.0.2.201", Port: 6443}))
+
+				var svcKey = types.NamespacedName{Namespace: virtinkClusterKey.Namespace, Name: virtinkClusterKey.Name}
+				Expect(apierrors.IsNotFound(k8sClient.Get(ctx, svcKey, &corev1.Service{}))).To(BeTrue())
+			})
+		})
+
+		Context("when the control plane service is not a LoadBalancer", func() {
+			BeforeEach(func() {
+				serviceType = corev1.ServiceTypeClusterIP
+			})
+
+			It("should report the misconfiguration without claiming an address", func() {
+				var virtinkCluster infrastructurev1beta1.VirtinkCluster
+				Eventually(func() string {
+					Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+					return conditions.GetReason(&virtinkCluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition)
+				}).Should(Equal(infrastructurev1beta1.ControlPlaneServiceProvisioningFailedReason))
+				Expect(*conditions.GetSeverity(&virtinkCluster, infrastructurev1beta1.ControlPlaneServiceReadyCondition)).To(Equal(capiv1beta1.ConditionSeverityError))
+
+				Consistently(func() bool {
+					return apierrors.IsNotFound(k8sClient.Get(ctx, ipClaimKey, &ipamv1.IPClaim{}))
+				}).Should(BeTrue())
+			})
+		})
+	})
+
+	Context("for a paused VirtinkCluster", func() {
+		var virtinkClusterKey types.NamespacedName
+		BeforeEach(func() {
+			By("creating a new paused VirtinkCluster")
+			virtinkClusterKey = types.NamespacedName{
+				Name:      "cluster-" + uuid.New().String(),
+				Namespace: "default",
+			}
+
+			virtinkCluster := infrastructurev1beta1.VirtinkCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      virtinkClusterKey.Name,
+					Namespace: virtinkClusterKey.Namespace,
+					Annotations: map[string]string{
+						capiv1beta1.PausedAnnotation: "",
+					},
+				},
+				Spec: infrastructurev1beta1.VirtinkClusterSpec{},
+			}
+			Expect(k8sClient.Create(ctx, &virtinkCluster)).To(Succeed())
+		})
+
+		It("should not add finalizer until unpaused", func() {
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Consistently(func() bool {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return controllerutil.ContainsFinalizer(&virtinkCluster, finalizer)
+			}).Should(BeFalse())
+
+			delete(virtinkCluster.Annotations, capiv1beta1.PausedAnnotation)
+			Expect(k8sClient.Update(ctx, &virtinkCluster)).To(Succeed())
+			Eventually(func() bool {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return controllerutil.ContainsFinalizer(&virtinkCluster, finalizer)
+			}).Should(BeTrue())
+		})
+	})
+
+	Context("for a VirtinkCluster with an identity not allowed in its namespace", func() {
+		var virtinkClusterKey types.NamespacedName
+		BeforeEach(func() {
+			By("creating a new VirtinkClusterIdentity")
+			identity := infrastructurev1beta1.VirtinkClusterIdentity{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "identity-" + uuid.New().String(),
+				},
+				Spec: infrastructurev1beta1.VirtinkClusterIdentitySpec{
+					SecretRef: corev1.SecretReference{
+						Name:      "infra-cluster-kubeconfig",
+						Namespace: "kube-system",
+					},
+					AllowedNamespaces: &infrastructurev1beta1.AllowedNamespaces{
+						NamespaceList: []string{"kube-system"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, &identity)).To(Succeed())
+
+			By("creating a new VirtinkCluster")
+			virtinkClusterKey = types.NamespacedName{
+				Name:      "cluster-" + uuid.New().String(),
+				Namespace: "default",
+			}
+
+			virtinkCluster := infrastructurev1beta1.VirtinkCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      virtinkClusterKey.Name,
+					Namespace: virtinkClusterKey.Namespace,
+				},
+				Spec: infrastructurev1beta1.VirtinkClusterSpec{
+					IdentityRef: &infrastructurev1beta1.VirtinkClusterIdentityReference{
+						Name: identity.Name,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, &virtinkCluster)).To(Succeed())
+		})
+
+		It("should refuse to use the identity", func() {
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Eventually(func() string {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return conditions.GetReason(&virtinkCluster, infrastructurev1beta1.InfraClusterReachableCondition)
+			}).Should(Equal(infrastructurev1beta1.InfraClusterIdentityForbiddenReason))
+			Expect(conditions.IsFalse(&virtinkCluster, infrastructurev1beta1.InfraClusterReachableCondition)).To(BeTrue())
+		})
+	})
+
+	Context("for a VirtinkCluster with an InfraClusterSecretRef in another namespace", func() {
+		var virtinkClusterKey types.NamespacedName
+		BeforeEach(func() {
+			By("creating a new infra cluster Secret")
+			secret := corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "infra-cluster-" + uuid.New().String(),
+					Namespace: "kube-system",
+				},
+				Data: map[string][]byte{
+					"server":                       []byte(cfg.Host),
+					corev1.ServiceAccountRootCAKey: cfg.CAData,
+					corev1.TLSCertKey:              cfg.CertData,
+					corev1.TLSPrivateKeyKey:        cfg.KeyData,
+				},
+			}
+			Expect(k8sClient.Create(ctx, &secret)).To(Succeed())
+
+			By("creating a new VirtinkCluster")
+			virtinkClusterKey = types.NamespacedName{
+				Name:      "cluster-" + uuid.New().String(),
+				Namespace: "default",
+			}
+
+			virtinkCluster := infrastructurev1beta1.VirtinkCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      virtinkClusterKey.Name,
+					Namespace: virtinkClusterKey.Namespace,
+				},
+				Spec: infrastructurev1beta1.VirtinkClusterSpec{
+					InfraClusterSecretRef: &corev1.ObjectReference{
+						Name:      secret.Name,
+						Namespace: secret.Namespace,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, &virtinkCluster)).To(Succeed())
+		})
+
+		It("should refuse to use the Secret but still allow deletion", func() {
+			var virtinkCluster infrastructurev1beta1.VirtinkCluster
+			Eventually(func() string {
+				Expect(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster)).To(Succeed())
+				return conditions.GetReason(&virtinkCluster, infrastructurev1beta1.InfraClusterReachableCondition)
+			}).Should(Equal(infrastructurev1beta1.InfraClusterIdentityForbiddenReason))
+			Expect(controllerutil.ContainsFinalizer(&virtinkCluster, finalizer)).To(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, &virtinkCluster)).To(Succeed())
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkClusterKey, &virtinkCluster))
+			}).Should(BeTrue())
 		})
 	})
 
@@ -2,11 +2,10 @@ package controllers
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"net"
+	"reflect"
 	"strings"
 	"time"
 
@@ -14,6 +13,7 @@ import (
 	virtv1alpha1 "github.com/smartxworks/virtink/pkg/apis/virt/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -21,11 +21,20 @@ import (
 	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	capierrors "sigs.k8s.io/cluster-api/errors"
+	capiipamv1alpha1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
 	capiutil "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	capipatch "sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	infrastructurev1beta1 "github.com/smartxworks/cluster-api-provider-virtink/api/v1beta1"
 )
@@ -35,6 +44,23 @@ type VirtinkMachineReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	Tracker  *InfraClusterTracker
+
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
+
+	controller controller.Controller
+
+	// DeletionTimeout is the duration after which a warning event is raised for a machine whose infra resources
+	// are still being torn down. Zero disables the warning.
+	DeletionTimeout time.Duration
+
+	// MACAddressPrefix is the prefix of the MAC addresses allocated to the interfaces of VMs. Defaults to 52:54:00.
+	MACAddressPrefix net.HardwareAddr
+
+	// DeterministicMACAddresses derives the MAC addresses from the names of the cluster, machine and interface
+	// instead of generating them randomly, so that recreated machines get the same MAC addresses.
+	DeterministicMACAddresses bool
 }
 
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=virtinkmachines,verbs=get;list;watch;create;update;patch;delete
@@ -44,12 +70,15 @@ type VirtinkMachineReconciler struct {
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;list;watch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines/status,verbs=get
 //+kubebuilder:rbac:groups=virt.virtink.smartx.com,resources=virtualmachines,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cdi.kubevirt.io,resources=datavolumes,verbs=get;list;watch;create;update;patch;delete;
-//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 //+kubebuilder:rbac:groups=ipam.metal3.io,resources=ipclaims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ipam.metal3.io,resources=ipclaims/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups=ipam.metal3.io,resources=ipaddresses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddressclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddresses,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -66,13 +95,32 @@ func (r *VirtinkMachineReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if annotations.HasPaused(&machine) {
+		ctrl.LoggerFrom(ctx).Info("reconciliation is paused for this object")
+		return ctrl.Result{}, nil
+	}
+
 	patchHelper, err := capipatch.NewHelper(&machine, r.Client)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("create Machine patch helper: %s", err)
 	}
 
 	defer func() {
-		if err := patchHelper.Patch(ctx, &machine); err != nil {
+		conditions.SetSummary(&machine, conditions.WithConditions(
+			infrastructurev1beta1.InfraClusterReachableCondition,
+			infrastructurev1beta1.BootstrapDataReadyCondition,
+			infrastructurev1beta1.IPAddressAllocatedCondition,
+			infrastructurev1beta1.VolumesReadyCondition,
+			infrastructurev1beta1.VMProvisionedCondition,
+		))
+		if err := patchHelper.Patch(ctx, &machine, capipatch.WithOwnedConditions{Conditions: []capiv1beta1.ConditionType{
+			capiv1beta1.ReadyCondition,
+			infrastructurev1beta1.InfraClusterReachableCondition,
+			infrastructurev1beta1.BootstrapDataReadyCondition,
+			infrastructurev1beta1.IPAddressAllocatedCondition,
+			infrastructurev1beta1.VolumesReadyCondition,
+			infrastructurev1beta1.VMProvisionedCondition,
+		}}); err != nil {
 			if rerr == nil {
 				rerr = fmt.Errorf("patch Machine: %s", err)
 			}
@@ -87,7 +135,7 @@ func (r *VirtinkMachineReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, rerr
+	return ctrl.Result{}, rerr
 }
 
 func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine) error {
@@ -95,6 +143,8 @@ func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infra
 	infraClusterClient := r.Client
 	var ownerMachine *capiv1beta1.Machine
 	var ownerCluster *capiv1beta1.Cluster
+	var cluster infrastructurev1beta1.VirtinkCluster
+	var infraNamespace string
 	if controllerutil.ContainsFinalizer(machine, finalizer) {
 		m, err := capiutil.GetOwnerMachine(ctx, r.Client, machine.ObjectMeta)
 		if err != nil {
@@ -102,6 +152,7 @@ func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infra
 		}
 		if m == nil {
 			log.Info("owner Machine is nil")
+			conditions.MarkFalse(machine, infrastructurev1beta1.BootstrapDataReadyCondition, infrastructurev1beta1.WaitingForOwnerMachineReason, capiv1beta1.ConditionSeverityInfo, "")
 			return nil
 		}
 		ownerMachine = m
@@ -112,11 +163,16 @@ func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infra
 		}
 		if c == nil {
 			log.Info("owner Cluster is nil")
+			conditions.MarkFalse(machine, infrastructurev1beta1.BootstrapDataReadyCondition, infrastructurev1beta1.WaitingForClusterInfrastructureReason, capiv1beta1.ConditionSeverityInfo, "")
 			return nil
 		}
 		ownerCluster = c
 
-		var cluster infrastructurev1beta1.VirtinkCluster
+		if ownerCluster.Spec.Paused {
+			log.Info("owner Cluster is paused")
+			return nil
+		}
+
 		clusterKey := types.NamespacedName{
 			Name:      ownerCluster.Spec.InfrastructureRef.Name,
 			Namespace: ownerCluster.Spec.InfrastructureRef.Namespace,
@@ -125,66 +181,40 @@ func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infra
 			return fmt.Errorf("get Cluster: %s", err)
 		}
 
-		if cluster.Spec.InfraClusterSecretRef != nil {
-			c, err := buildInfraClusterClient(ctx, r.Client, cluster.Spec.InfraClusterSecretRef)
+		// The failure domain is recorded once, so that the VM and DataVolumes are always tracked and deleted in the
+		// infra cluster they are created in.
+		if machine.Status.FailureDomain == nil {
+			machine.Status.FailureDomain = ownerMachine.Spec.FailureDomain
+		}
+
+		infraClusterSecretRef, err := r.getInfraCluster(ctx, &cluster, machine)
+		if err != nil {
+			return err
+		}
+		infraNamespace = machine.Status.InfraNamespace
+		// The client of the infra cluster the VirtinkMachine used to be placed in is not needed anymore.
+		r.Tracker.ReleaseClients(machine, infraClusterSecretRef)
+		if infraClusterSecretRef != nil {
+			c, err := r.Tracker.GetClient(ctx, machine, infraClusterSecretRef, infraNamespace)
 			if err != nil {
+				conditions.MarkFalse(machine, infrastructurev1beta1.InfraClusterReachableCondition, infrastructurev1beta1.InfraClusterClientFailedReason, capiv1beta1.ConditionSeverityError, err.Error())
 				return fmt.Errorf("build infra cluster client: %s", err)
 			}
 			infraClusterClient = c
-		}
-	}
 
-	infraNamespace := machine.Namespace
-	if machine.Spec.VirtualMachineTemplate.ObjectMeta.Namespace != "" {
-		infraNamespace = machine.Spec.VirtualMachineTemplate.ObjectMeta.Namespace
+			if err := r.watchInfraCluster(ctx, infraClusterSecretRef, machine); err != nil {
+				conditions.MarkFalse(machine, infrastructurev1beta1.InfraClusterReachableCondition, infrastructurev1beta1.InfraClusterClientFailedReason, capiv1beta1.ConditionSeverityError, err.Error())
+				return fmt.Errorf("watch infra cluster: %s", err)
+			}
+		}
+		conditions.MarkTrue(machine, infrastructurev1beta1.InfraClusterReachableCondition)
 	}
 
 	if !machine.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(machine, finalizer) {
-			var vm virtv1alpha1.VirtualMachine
-			vmKey := types.NamespacedName{
-				Name:      machine.Name,
-				Namespace: infraNamespace,
-			}
-			vmNotFound := false
-			if err := infraClusterClient.Get(ctx, vmKey, &vm); err != nil {
-				if apierrors.IsNotFound(err) {
-					vmNotFound = true
-				} else {
-					return fmt.Errorf("get VM: %s", err)
-				}
-			}
-
-			if !vmNotFound {
-				if err := infraClusterClient.Delete(ctx, &vm); err != nil {
-					return fmt.Errorf("delete VM: %s", err)
-				}
-				r.Recorder.Eventf(machine, corev1.EventTypeNormal, "DeletedVM", "Deleted VM %q", vm.Name)
+			if err := r.reconcileDelete(ctx, infraClusterClient, machine, infraNamespace); err != nil {
+				return err
 			}
-
-			if machine.Spec.IPPoolRef != nil {
-				var ipClaim ipamv1.IPClaim
-				ipClaimKey := types.NamespacedName{
-					Name:      machine.Name,
-					Namespace: machine.Namespace,
-				}
-				ipClaimNotFound := false
-				if err := r.Get(ctx, ipClaimKey, &ipClaim); err != nil {
-					if apierrors.IsNotFound(err) {
-						ipClaimNotFound = true
-					} else {
-						return fmt.Errorf("get ipClaim: %s", err)
-					}
-				}
-				if !ipClaimNotFound {
-					controllerutil.RemoveFinalizer(&ipClaim, finalizer)
-					if err := r.Update(ctx, &ipClaim); err != nil {
-						return fmt.Errorf("update ipClaim: %s", err)
-					}
-				}
-			}
-
-			controllerutil.RemoveFinalizer(machine, finalizer)
 		}
 	} else {
 		if !controllerutil.ContainsFinalizer(machine, finalizer) {
@@ -194,19 +224,46 @@ func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infra
 
 		if !ownerCluster.Status.InfrastructureReady {
 			log.Info("owner Cluster is not ready")
-			return reconcileError{Result: ctrl.Result{RequeueAfter: 3 * time.Second}}
+			conditions.MarkFalse(machine, infrastructurev1beta1.BootstrapDataReadyCondition, infrastructurev1beta1.WaitingForClusterInfrastructureReason, capiv1beta1.ConditionSeverityInfo, "")
+			return nil
 		}
 
 		if ownerMachine.Spec.Bootstrap.DataSecretName == nil {
 			log.Info("bootstrap data is nil")
-			return reconcileError{Result: ctrl.Result{RequeueAfter: 3 * time.Second}}
+			conditions.MarkFalse(machine, infrastructurev1beta1.BootstrapDataReadyCondition, infrastructurev1beta1.WaitingForBootstrapDataReason, capiv1beta1.ConditionSeverityInfo, "")
+			return nil
+		}
+		if err := r.reconcileBootstrapDataSecret(ctx, infraClusterClient, machine, ownerMachine, infraNamespace); err != nil {
+			return err
 		}
+		conditions.MarkTrue(machine, infrastructurev1beta1.BootstrapDataReadyCondition)
 
-		if err := r.ensureMachineAddress(ctx, machine); err != nil {
+		var vm virtv1alpha1.VirtualMachine
+		vmKey := types.NamespacedName{
+			Name:      machine.Name,
+			Namespace: infraNamespace,
+		}
+		vmNotFound := false
+		if err := infraClusterClient.Get(ctx, vmKey, &vm); err != nil {
+			if apierrors.IsNotFound(err) {
+				vmNotFound = true
+			} else {
+				conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.VMProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+				return fmt.Errorf("get VM: %s", err)
+			}
+		}
+
+		var existingVM *virtv1alpha1.VirtualMachine
+		if !vmNotFound {
+			existingVM = &vm
+		}
+		interfaceAddresses, err := r.ensureMachineAddresses(ctx, machine, existingVM)
+		if err != nil {
 			return err
 		}
 
-		dataVolumes := r.buildDataVolumes(ctx, machine)
+		dataVolumes := r.buildDataVolumes(ctx, machine, infraNamespace)
+		createdDataVolumes := []cdiv1beta1.DataVolume{}
 		for _, dataVolume := range dataVolumes {
 			dataVolumeKey := types.NamespacedName{
 				Namespace: dataVolume.Namespace,
@@ -216,6 +273,7 @@ func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infra
 			createdDataVolume := cdiv1beta1.DataVolume{}
 			if err := infraClusterClient.Get(ctx, dataVolumeKey, &createdDataVolume); err != nil {
 				if !apierrors.IsNotFound(err) {
+					conditions.MarkFalse(machine, infrastructurev1beta1.VolumesReadyCondition, infrastructurev1beta1.DataVolumeProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
 					return fmt.Errorf("get DataVolume: %s", err)
 				}
 				dataVolumeNotFound = true
@@ -229,67 +287,84 @@ func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infra
 				var pvc corev1.PersistentVolumeClaim
 				if err := infraClusterClient.Get(ctx, pvcKey, &pvc); err != nil {
 					if !apierrors.IsNotFound(err) {
+						conditions.MarkFalse(machine, infrastructurev1beta1.VolumesReadyCondition, infrastructurev1beta1.DataVolumeProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
 						return fmt.Errorf("get PVC: %s", err)
 					}
 					pvcNotFound = true
 				}
 				if pvcNotFound {
 					if err := infraClusterClient.Create(ctx, dataVolume); err != nil {
+						conditions.MarkFalse(machine, infrastructurev1beta1.VolumesReadyCondition, infrastructurev1beta1.DataVolumeProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
 						return fmt.Errorf("create DataVolume: %s", err)
 					}
 					r.Recorder.Eventf(machine, corev1.EventTypeNormal, "CreatedDataVolume", "Created DataVolume %q", dataVolume.Name)
+					createdDataVolumes = append(createdDataVolumes, *dataVolume)
 				}
-			}
-		}
-
-		var vm virtv1alpha1.VirtualMachine
-		vmKey := types.NamespacedName{
-			Name:      machine.Name,
-			Namespace: infraNamespace,
-		}
-		vmNotFound := false
-		if err := infraClusterClient.Get(ctx, vmKey, &vm); err != nil {
-			if apierrors.IsNotFound(err) {
-				vmNotFound = true
 			} else {
-				return fmt.Errorf("get VM: %s", err)
+				if err := ensureVirtinkMachineLabels(ctx, infraClusterClient, &createdDataVolume, machine); err != nil {
+					conditions.MarkFalse(machine, infrastructurev1beta1.VolumesReadyCondition, infrastructurev1beta1.DataVolumeProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+					return fmt.Errorf("label DataVolume: %s", err)
+				}
+				createdDataVolumes = append(createdDataVolumes, createdDataVolume)
 			}
 		}
+		markVolumesReady(machine, createdDataVolumes)
 
 		if vmNotFound {
-			vm, err := r.buildVM(ctx, machine, ownerMachine)
+			vm, err := r.buildVM(ctx, &cluster, machine, interfaceAddresses)
 			if err != nil {
+				conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.VMProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
 				return fmt.Errorf("build VM: %s", err)
 			}
 
 			vm.Name = vmKey.Name
 			vm.Namespace = vmKey.Namespace
 			if err := infraClusterClient.Create(ctx, vm); err != nil {
+				conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.VMProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
 				return fmt.Errorf("create VM: %s", err)
 			}
 			r.Recorder.Eventf(machine, corev1.EventTypeNormal, "CreatedVM", "Created VM %q", vm.Name)
-			return reconcileError{Result: ctrl.Result{RequeueAfter: 10 * time.Second}}
+			conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.WaitingForVMSchedulingReason, capiv1beta1.ConditionSeverityInfo, "")
+			return nil
+		}
+
+		if err := ensureVirtinkMachineLabels(ctx, infraClusterClient, &vm, machine); err != nil {
+			conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.VMProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return fmt.Errorf("label VM: %s", err)
 		}
 
 		providerID := fmt.Sprintf("virtink://%s", vm.UID)
 		machine.Spec.ProviderID = &providerID
 		machine.Status.Ready = false
 
+		addresses, err := r.buildMachineAddresses(ctx, infraClusterClient, machine, &vm, interfaceAddresses)
+		if err != nil {
+			return fmt.Errorf("build machine addresses: %s", err)
+		}
+		machine.Status.Addresses = addresses
+
 		failureReason := capierrors.UpdateMachineError
 		switch vm.Status.Phase {
 		case virtv1alpha1.VirtualMachinePending, virtv1alpha1.VirtualMachineScheduling, virtv1alpha1.VirtualMachineScheduled:
-			return reconcileError{Result: ctrl.Result{RequeueAfter: 10 * time.Second}}
+			conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.WaitingForVMSchedulingReason, capiv1beta1.ConditionSeverityInfo, "VM is %s", vm.Status.Phase)
 		case virtv1alpha1.VirtualMachineRunning:
+			conditions.MarkTrue(machine, infrastructurev1beta1.VMProvisionedCondition)
 			machine.Status.Ready = true
 		case virtv1alpha1.VirtualMachineFailed:
 			if vm.Spec.RunPolicy == virtv1alpha1.RunPolicyHalted || vm.Spec.RunPolicy == virtv1alpha1.RunPolicyOnce {
 				machine.Status.FailureReason = &failureReason
 				machine.Status.FailureMessage = &[]string{"VM has reached final state"}[0]
+				conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.VMTerminatedReason, capiv1beta1.ConditionSeverityError, "VM is %s", vm.Status.Phase)
+			} else {
+				conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.WaitingForVMSchedulingReason, capiv1beta1.ConditionSeverityInfo, "VM is %s", vm.Status.Phase)
 			}
 		case virtv1alpha1.VirtualMachineSucceeded:
 			if vm.Spec.RunPolicy == virtv1alpha1.RunPolicyHalted || vm.Spec.RunPolicy == virtv1alpha1.RunPolicyOnce || vm.Spec.RunPolicy == virtv1alpha1.RunPolicyRerunOnFailure {
 				machine.Status.FailureReason = &failureReason
 				machine.Status.FailureMessage = &[]string{"VM has reached final state"}[0]
+				conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.VMTerminatedReason, capiv1beta1.ConditionSeverityError, "VM is %s", vm.Status.Phase)
+			} else {
+				conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.WaitingForVMSchedulingReason, capiv1beta1.ConditionSeverityInfo, "VM is %s", vm.Status.Phase)
 			}
 		}
 	}
@@ -297,20 +372,418 @@ func (r *VirtinkMachineReconciler) reconcile(ctx context.Context, machine *infra
 	return nil
 }
 
-func (r *VirtinkMachineReconciler) ensureMachineAddress(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine) error {
-	if machine.Spec.IPPoolRef == nil {
+// reconcileDelete tears down the infra resources of the machine step by step: the VM is deleted and waited to
+// disappear, then the DataVolumes are deleted and waited to disappear, then the IP address is released, and at
+// last the finalizer is removed. Each step is recorded in the conditions of the machine and requeues until done.
+func (r *VirtinkMachineReconciler) reconcileDelete(ctx context.Context, infraClusterClient client.Client, machine *infrastructurev1beta1.VirtinkMachine, infraNamespace string) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	var vm virtv1alpha1.VirtualMachine
+	vmKey := types.NamespacedName{
+		Name:      machine.Name,
+		Namespace: infraNamespace,
+	}
+	vmNotFound := false
+	if err := infraClusterClient.Get(ctx, vmKey, &vm); err != nil {
+		if apierrors.IsNotFound(err) {
+			vmNotFound = true
+		} else {
+			conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, capiv1beta1.DeletionFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return fmt.Errorf("get VM: %s", err)
+		}
+	}
+
+	if !vmNotFound {
+		if vm.DeletionTimestamp.IsZero() {
+			if err := infraClusterClient.Delete(ctx, &vm); client.IgnoreNotFound(err) != nil {
+				conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, capiv1beta1.DeletionFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+				return fmt.Errorf("delete VM: %s", err)
+			}
+			r.Recorder.Eventf(machine, corev1.EventTypeNormal, "DeletedVM", "Deleted VM %q", vm.Name)
+		}
+		log.Info("waiting for VM to be deleted")
+		return r.waitForDeletion(machine, infrastructurev1beta1.VMProvisionedCondition, fmt.Sprintf("VM %q", vm.Name))
+	}
+	conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, capiv1beta1.DeletedReason, capiv1beta1.ConditionSeverityInfo, "")
+
+	if err := r.deleteBootstrapDataSecret(ctx, infraClusterClient, machine, infraNamespace); err != nil {
+		conditions.MarkFalse(machine, infrastructurev1beta1.BootstrapDataReadyCondition, capiv1beta1.DeletionFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return fmt.Errorf("delete bootstrap data Secret: %s", err)
+	}
+
+	dataVolumesDeleted, err := r.deleteDataVolumes(ctx, infraClusterClient, machine, infraNamespace)
+	if err != nil {
+		conditions.MarkFalse(machine, infrastructurev1beta1.VolumesReadyCondition, capiv1beta1.DeletionFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return fmt.Errorf("delete DataVolumes: %s", err)
+	}
+	if !dataVolumesDeleted {
+		log.Info("waiting for DataVolumes to be deleted")
+		return r.waitForDeletion(machine, infrastructurev1beta1.VolumesReadyCondition, "DataVolumes")
+	}
+	if conditions.Has(machine, infrastructurev1beta1.VolumesReadyCondition) {
+		conditions.MarkFalse(machine, infrastructurev1beta1.VolumesReadyCondition, capiv1beta1.DeletedReason, capiv1beta1.ConditionSeverityInfo, "")
+	}
+
+	bindings := ipPoolBindings(machine)
+	for _, binding := range bindings {
+		if err := r.releaseIPAddress(ctx, machine, binding); err != nil {
+			conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, capiv1beta1.DeletionFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return err
+		}
+	}
+	if len(bindings) > 0 {
+		conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, capiv1beta1.DeletedReason, capiv1beta1.ConditionSeverityInfo, "")
+	}
+
+	controllerutil.RemoveFinalizer(machine, finalizer)
+	r.Tracker.ReleaseClients(machine)
+	return nil
+}
+
+// reconcileBootstrapDataSecret copies the bootstrap data of the owner Machine into a Secret in the infra namespace,
+// which is referenced by the cloud-init volume of the VM, so that the bootstrap data is not exposed in the VM spec.
+// Only cloud-config is supported, since the user data of the NoCloud disk is the only way to pass the bootstrap data
+// to the VM, which Ignition does not read.
+func (r *VirtinkMachineReconciler) reconcileBootstrapDataSecret(ctx context.Context, infraClusterClient client.Client, machine *infrastructurev1beta1.VirtinkMachine, ownerMachine *capiv1beta1.Machine, infraNamespace string) error {
+	var bootstrapSecret corev1.Secret
+	bootstrapSecretKey := types.NamespacedName{
+		Namespace: machine.Namespace,
+		Name:      *ownerMachine.Spec.Bootstrap.DataSecretName,
+	}
+	if err := r.Get(ctx, bootstrapSecretKey, &bootstrapSecret); err != nil {
+		conditions.MarkFalse(machine, infrastructurev1beta1.BootstrapDataReadyCondition, infrastructurev1beta1.BootstrapDataSecretSyncFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return fmt.Errorf("get bootstrap Secret: %s", err)
+	}
+
+	// Bootstrap providers not setting the format only support cloud-config.
+	format := string(bootstrapSecret.Data[bootstrapDataFormatSecretKey])
+	if format == "" {
+		format = bootstrapDataFormatCloudConfig
+	}
+	if format != bootstrapDataFormatCloudConfig {
+		message := fmt.Sprintf("unsupported bootstrap data format %q", format)
+		failureReason := capierrors.InvalidConfigurationMachineError
+		machine.Status.FailureReason = &failureReason
+		machine.Status.FailureMessage = &message
+		conditions.MarkFalse(machine, infrastructurev1beta1.BootstrapDataReadyCondition, infrastructurev1beta1.UnsupportedBootstrapDataFormatReason, capiv1beta1.ConditionSeverityError, message)
+		r.Recorder.Event(machine, corev1.EventTypeWarning, "UnsupportedBootstrapDataFormat", message)
+		return reconcileError{Result: ctrl.Result{Requeue: false}}
+	}
+
+	data := map[string][]byte{
+		bootstrapDataSecretKey:       bootstrapSecret.Data[bootstrapDataSecretKey],
+		bootstrapDataFormatSecretKey: []byte(format),
+	}
+	if err := r.syncBootstrapDataSecret(ctx, infraClusterClient, machine, infraNamespace, data); err != nil {
+		conditions.MarkFalse(machine, infrastructurev1beta1.BootstrapDataReadyCondition, infrastructurev1beta1.BootstrapDataSecretSyncFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return fmt.Errorf("sync bootstrap data Secret: %s", err)
+	}
+	return nil
+}
+
+func (r *VirtinkMachineReconciler) syncBootstrapDataSecret(ctx context.Context, infraClusterClient client.Client, machine *infrastructurev1beta1.VirtinkMachine, infraNamespace string, data map[string][]byte) error {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{
+		Namespace: infraNamespace,
+		Name:      bootstrapDataSecretName(machine),
+	}
+	if err := infraClusterClient.Get(ctx, secretKey, &secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("get Secret: %s", err)
+		}
+
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: secretKey.Namespace,
+				Name:      secretKey.Name,
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		setVirtinkMachineLabels(&secret, machine)
+		if err := infraClusterClient.Create(ctx, &secret); err != nil {
+			return fmt.Errorf("create Secret: %s", err)
+		}
+		r.Recorder.Eventf(machine, corev1.EventTypeNormal, "CreatedBootstrapDataSecret", "Created bootstrap data Secret %q", secret.Name)
+		return nil
+	}
+
+	// A Secret of the same name not created for the machine is left alone, since it may be used by others.
+	if !isCreatedForVirtinkMachine(&secret, machine) {
+		return fmt.Errorf("bootstrap data Secret %q already exists and is not created for the VirtinkMachine", secret.Name)
+	}
+	if reflect.DeepEqual(secret.Data, data) {
+		return nil
+	}
+	secret.Data = data
+	if err := infraClusterClient.Update(ctx, &secret); err != nil {
+		return fmt.Errorf("update Secret: %s", err)
+	}
+	r.Recorder.Eventf(machine, corev1.EventTypeNormal, "UpdatedBootstrapDataSecret", "Updated bootstrap data Secret %q", secret.Name)
+	return nil
+}
+
+func (r *VirtinkMachineReconciler) deleteBootstrapDataSecret(ctx context.Context, infraClusterClient client.Client, machine *infrastructurev1beta1.VirtinkMachine, infraNamespace string) error {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{
+		Namespace: infraNamespace,
+		Name:      bootstrapDataSecretName(machine),
+	}
+	if err := infraClusterClient.Get(ctx, secretKey, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	// The Secret is only deleted if it was created for the machine.
+	if !isCreatedForVirtinkMachine(&secret, machine) {
 		return nil
 	}
+	if err := infraClusterClient.Delete(ctx, &secret); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	r.Recorder.Eventf(machine, corev1.EventTypeNormal, "DeletedBootstrapDataSecret", "Deleted bootstrap data Secret %q", secret.Name)
+	return nil
+}
+
+const (
+	// bootstrapDataSecretKey is the key of the bootstrap data in both the bootstrap Secret created by the bootstrap
+	// provider and the Secret referenced by the cloud-init volume of the VM.
+	bootstrapDataSecretKey = "value"
+
+	// bootstrapDataFormatSecretKey is the key of the format of the bootstrap data, e.g. cloud-config or ignition.
+	bootstrapDataFormatSecretKey = "format"
+
+	bootstrapDataFormatCloudConfig = "cloud-config"
+)
+
+// bootstrapDataSecretName returns the name of the Secret the bootstrap data is copied into, which differs from the name
+// of the bootstrap Secret of the machine, since both are in the same namespace when the management cluster is used for
+// infra.
+func bootstrapDataSecretName(machine *infrastructurev1beta1.VirtinkMachine) string {
+	return machine.Name + "-userdata"
+}
+
+// waitForDeletion marks the condition as being deleted and requeues the machine. A warning event is raised once
+// the deletion of the machine takes longer than DeletionTimeout, i.e. when the condition turns into a warning.
+func (r *VirtinkMachineReconciler) waitForDeletion(machine *infrastructurev1beta1.VirtinkMachine, conditionType capiv1beta1.ConditionType, target string) error {
+	if r.DeletionTimeout > 0 && time.Since(machine.DeletionTimestamp.Time) > r.DeletionTimeout {
+		severity := conditions.GetSeverity(machine, conditionType)
+		warned := conditions.GetReason(machine, conditionType) == capiv1beta1.DeletingReason && severity != nil && *severity == capiv1beta1.ConditionSeverityWarning
+		message := fmt.Sprintf("%s has not been deleted within %s", target, r.DeletionTimeout)
+		conditions.MarkFalse(machine, conditionType, capiv1beta1.DeletingReason, capiv1beta1.ConditionSeverityWarning, message)
+		if !warned {
+			r.Recorder.Event(machine, corev1.EventTypeWarning, "DeletionTimeout", message)
+		}
+	} else {
+		conditions.MarkFalse(machine, conditionType, capiv1beta1.DeletingReason, capiv1beta1.ConditionSeverityInfo, "waiting for %s to be deleted", target)
+	}
+	return reconcileError{Result: ctrl.Result{RequeueAfter: 3 * time.Second}}
+}
+
+// ipPoolBinding is an IP pool to allocate an IP address of the interface of the VM from.
+type ipPoolBinding struct {
+	InterfaceName string
+	IPPoolRef     corev1.TypedLocalObjectReference
+	IPClaimName   string
+}
+
+// ipPoolBindings returns the IP pools of the machine. The IP address allocated from the IPPoolRef is bound to the first
+// interface of the VM, and its IP claim is named after the machine.
+func ipPoolBindings(machine *infrastructurev1beta1.VirtinkMachine) []ipPoolBinding {
+	var bindings []ipPoolBinding
+	if machine.Spec.IPPoolRef != nil {
+		var interfaceName string
+		if interfaces := machine.Spec.VirtualMachineTemplate.Spec.Instance.Interfaces; len(interfaces) > 0 {
+			interfaceName = interfaces[0].Name
+		}
+		bindings = append(bindings, ipPoolBinding{
+			InterfaceName: interfaceName,
+			IPPoolRef:     *machine.Spec.IPPoolRef,
+			IPClaimName:   machine.Name,
+		})
+	}
+	for _, interfaceIPPool := range machine.Spec.InterfaceIPPools {
+		bindings = append(bindings, ipPoolBinding{
+			InterfaceName: interfaceIPPool.InterfaceName,
+			IPPoolRef:     interfaceIPPool.IPPoolRef,
+			IPClaimName:   fmt.Sprintf("%s-%s-%s", machine.Name, interfaceIPPool.InterfaceName, interfaceIPPool.IPPoolRef.Name),
+		})
+	}
+	return bindings
+}
+
+// ensureMachineAddresses allocates a MAC address for each interface of the VM, so that the interfaces keep their MAC
+// addresses when the VM is recreated and can all be matched in the network config, and an IP address from each IP pool
+// of the machine. The MAC addresses of the existing VM, if any, are recorded before allocating any. The $IP_ADDRESS and
+// $MAC_ADDRESS placeholders in the annotations of the machine are replaced by the first allocated IP address and its
+// MAC address.
+func (r *VirtinkMachineReconciler) ensureMachineAddresses(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine, existingVM *virtv1alpha1.VirtualMachine) ([]interfaceAddress, error) {
+	if existingVM != nil {
+		backfillMACAddresses(machine, existingVM)
+	}
+	for _, iface := range machine.Spec.VirtualMachineTemplate.Spec.Instance.Interfaces {
+		if _, err := r.ensureMACAddress(machine, iface.Name); err != nil {
+			conditions.MarkFalse(machine, infrastructurev1beta1.VMProvisionedCondition, infrastructurev1beta1.VMProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, fmt.Errorf("ensure MAC address: %s", err)
+		}
+	}
+
+	bindings := ipPoolBindings(machine)
+	if len(bindings) == 0 {
+		conditions.Delete(machine, infrastructurev1beta1.IPAddressAllocatedCondition)
+		return nil, nil
+	}
+
+	var addresses []interfaceAddress
+	allAllocated := true
+	for _, binding := range bindings {
+		ipAddress, err := r.ensureIPAddress(ctx, machine, binding)
+		if err != nil {
+			return nil, err
+		}
+		if ipAddress == nil {
+			allAllocated = false
+			continue
+		}
+		ipAddress.InterfaceName = binding.InterfaceName
+		addresses = append(addresses, *ipAddress)
+	}
+	if !allAllocated {
+		conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.WaitingForIPAddressReason, capiv1beta1.ConditionSeverityInfo, "")
+		return nil, reconcileError{Result: ctrl.Result{RequeueAfter: 1 * time.Second}}
+	}
+
+	for i := range addresses {
+		macAddress, err := r.ensureMACAddress(machine, addresses[i].InterfaceName)
+		if err != nil {
+			conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, fmt.Errorf("ensure MAC address: %s", err)
+		}
+		addresses[i].MAC = macAddress
+	}
+
+	replacer := strings.NewReplacer("$IP_ADDRESS", addresses[0].Address, "$MAC_ADDRESS", addresses[0].MAC.String())
+	for name, value := range machine.Annotations {
+		machine.Annotations[name] = replacer.Replace(value)
+	}
+
+	conditions.MarkTrue(machine, infrastructurev1beta1.IPAddressAllocatedCondition)
+	return addresses, nil
+}
+
+// ensureMACAddress returns the MAC address of the interface recorded in the status of the machine. If there is none,
+// the MAC address set on the interface in the VirtualMachineTemplate is used, otherwise one is allocated with the
+// MACAddressPrefix. The MAC address is recorded in the status, so that it is kept for the lifetime of the machine.
+func (r *VirtinkMachineReconciler) ensureMACAddress(machine *infrastructurev1beta1.VirtinkMachine, interfaceName string) (net.HardwareAddr, error) {
+	if macAddress := recordedMACAddress(machine, interfaceName); macAddress != "" {
+		return net.ParseMAC(macAddress)
+	}
+
+	var macAddress net.HardwareAddr
+	for _, iface := range machine.Spec.VirtualMachineTemplate.Spec.Instance.Interfaces {
+		if iface.Name == interfaceName && iface.MAC != "" {
+			mac, err := net.ParseMAC(iface.MAC)
+			if err != nil {
+				return nil, fmt.Errorf("parse MAC address of interface %q: %s", iface.Name, err)
+			}
+			macAddress = mac
+		}
+	}
+
+	if macAddress == nil {
+		prefix := r.MACAddressPrefix
+		if len(prefix) == 0 {
+			prefix = defaultMACAddressPrefix
+		}
+		if r.DeterministicMACAddresses {
+			seed := strings.Join([]string{machine.Namespace, machine.Labels[capiv1beta1.ClusterLabelName], machine.Name, interfaceName}, "/")
+			macAddress = deriveMAC(prefix, seed)
+		} else {
+			mac, err := generateMAC(prefix)
+			if err != nil {
+				return nil, err
+			}
+			macAddress = mac
+		}
+	}
+
+	machine.Status.MACAddresses = append(machine.Status.MACAddresses, infrastructurev1beta1.InterfaceMACAddress{
+		InterfaceName: interfaceName,
+		MACAddress:    macAddress.String(),
+	})
+	return macAddress, nil
+}
+
+// backfillMACAddresses records the MAC addresses of the interfaces of the existing VM which are not recorded in the
+// status of the machine, e.g. of a VM created before MAC addresses were recorded, so that they are not allocated anew.
+func backfillMACAddresses(machine *infrastructurev1beta1.VirtinkMachine, vm *virtv1alpha1.VirtualMachine) {
+	interfaceNames := []string{""}
+	if interfaces := machine.Spec.VirtualMachineTemplate.Spec.Instance.Interfaces; len(interfaces) > 0 {
+		interfaceNames = nil
+		for _, iface := range interfaces {
+			interfaceNames = append(interfaceNames, iface.Name)
+		}
+	}
+
+	for _, interfaceName := range interfaceNames {
+		if recordedMACAddress(machine, interfaceName) != "" {
+			continue
+		}
+		for i, iface := range vm.Spec.Instance.Interfaces {
+			// The interface of the MAC address recorded without interface name is the first one of the VM.
+			if (iface.Name == interfaceName || interfaceName == "" && i == 0) && iface.MAC != "" {
+				machine.Status.MACAddresses = append(machine.Status.MACAddresses, infrastructurev1beta1.InterfaceMACAddress{
+					InterfaceName: interfaceName,
+					MACAddress:    iface.MAC,
+				})
+				break
+			}
+		}
+	}
+}
+
+// recordedMACAddress returns the MAC address of the interface recorded in the status of the machine, or an empty string
+// if there is none.
+func recordedMACAddress(machine *infrastructurev1beta1.VirtinkMachine, interfaceName string) string {
+	for _, macAddress := range machine.Status.MACAddresses {
+		if macAddress.InterfaceName == interfaceName {
+			return macAddress.MACAddress
+		}
+	}
+	return ""
+}
 
+// ensureIPAddress claims an IP address from the IP pool of the binding, by an IPClaim of the metal3 IPAM or an
+// IPAddressClaim of a Cluster API IPAM provider depending on the API group of the IP pool. A nil IP address is returned
+// if the IP address is not allocated yet.
+func (r *VirtinkMachineReconciler) ensureIPAddress(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine, binding ipPoolBinding) (*interfaceAddress, error) {
+	switch ipPoolAPIGroup(binding.IPPoolRef) {
+	case ipamv1.GroupVersion.Group:
+		return r.ensureMetal3IPAddress(ctx, machine, binding)
+	case capiipamv1alpha1.GroupVersion.Group:
+		return r.ensureIPAddressClaimAddress(ctx, machine, binding)
+	default:
+		message := fmt.Sprintf("unsupported IP pool API group %q", ipPoolAPIGroup(binding.IPPoolRef))
+		failureReason := capierrors.InvalidConfigurationMachineError
+		machine.Status.FailureReason = &failureReason
+		machine.Status.FailureMessage = &message
+		conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPAddressAllocationFailedReason, capiv1beta1.ConditionSeverityError, message)
+		return nil, reconcileError{Result: ctrl.Result{Requeue: false}}
+	}
+}
+
+func (r *VirtinkMachineReconciler) ensureMetal3IPAddress(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine, binding ipPoolBinding) (*interfaceAddress, error) {
 	ipClaimKey := types.NamespacedName{
-		Name:      machine.Name,
+		Name:      binding.IPClaimName,
 		Namespace: machine.Namespace,
 	}
 	var ipClaim ipamv1.IPClaim
 	var ipClaimNotFound bool
 	if err := r.Get(ctx, ipClaimKey, &ipClaim); err != nil {
 		if !apierrors.IsNotFound(err) {
-			return err
+			conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, err
 		}
 		ipClaimNotFound = true
 	}
@@ -325,15 +798,17 @@ func (r *VirtinkMachineReconciler) ensureMachineAddress(ctx context.Context, mac
 			Spec: ipamv1.IPClaimSpec{
 				Pool: corev1.ObjectReference{
 					Namespace: machine.Namespace,
-					Name:      machine.Spec.IPPoolRef.Name,
+					Name:      binding.IPPoolRef.Name,
 				},
 			},
 		}
 		if err := controllerutil.SetOwnerReference(machine, &ipClaim, r.Scheme); err != nil {
-			return err
+			conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, err
 		}
 		if err := r.Create(ctx, &ipClaim); err != nil {
-			return err
+			conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, err
 		}
 	}
 
@@ -341,11 +816,12 @@ func (r *VirtinkMachineReconciler) ensureMachineAddress(ctx context.Context, mac
 		failureReason := capierrors.InvalidConfigurationMachineError
 		machine.Status.FailureReason = &failureReason
 		machine.Status.FailureMessage = ipClaim.Status.ErrorMessage
-		return reconcileError{Result: ctrl.Result{Requeue: false}}
+		conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPAddressAllocationFailedReason, capiv1beta1.ConditionSeverityError, *ipClaim.Status.ErrorMessage)
+		return nil, reconcileError{Result: ctrl.Result{Requeue: false}}
 	}
 
 	if ipClaim.Status.Address == nil {
-		return reconcileError{Result: ctrl.Result{RequeueAfter: 1 * time.Second}}
+		return nil, nil
 	}
 
 	var ipAddress ipamv1.IPAddress
@@ -354,29 +830,214 @@ func (r *VirtinkMachineReconciler) ensureMachineAddress(ctx context.Context, mac
 		Name:      ipClaim.Status.Address.Name,
 	}
 	if err := r.Get(ctx, ipAddressKey, &ipAddress); err != nil {
-		return err
+		conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return nil, err
 	}
 
-	macAddress, err := generateMAC()
-	if err != nil {
-		return fmt.Errorf("generate MAC address: %s", err)
+	address := &interfaceAddress{
+		Address: string(ipAddress.Spec.Address),
+		Prefix:  ipAddress.Spec.Prefix,
 	}
+	if ipAddress.Spec.Gateway != nil {
+		address.Gateway = string(*ipAddress.Spec.Gateway)
+	}
+	for _, dnsServer := range ipAddress.Spec.DNSServers {
+		address.DNSServers = append(address.DNSServers, string(dnsServer))
+	}
+	return address, nil
+}
 
-	replacer := strings.NewReplacer("$IP_ADDRESS", string(ipAddress.Spec.Address), "$MAC_ADDRESS", macAddress.String())
-	for name, value := range machine.Annotations {
-		machine.Annotations[name] = replacer.Replace(value)
+func (r *VirtinkMachineReconciler) ensureIPAddressClaimAddress(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine, binding ipPoolBinding) (*interfaceAddress, error) {
+	var ipAddressClaim capiipamv1alpha1.IPAddressClaim
+	ipAddressClaimKey := types.NamespacedName{
+		Name:      binding.IPClaimName,
+		Namespace: machine.Namespace,
+	}
+	if err := r.Get(ctx, ipAddressClaimKey, &ipAddressClaim); err != nil {
+		if !apierrors.IsNotFound(err) {
+			conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, fmt.Errorf("get IPAddressClaim: %s", err)
+		}
+
+		ipAddressClaim = capiipamv1alpha1.IPAddressClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ipAddressClaimKey.Name,
+				Namespace: ipAddressClaimKey.Namespace,
+			},
+			Spec: capiipamv1alpha1.IPAddressClaimSpec{
+				PoolRef: binding.IPPoolRef,
+			},
+		}
+		if err := controllerutil.SetOwnerReference(machine, &ipAddressClaim, r.Scheme); err != nil {
+			conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, fmt.Errorf("set IPAddressClaim owner: %s", err)
+		}
+		if err := r.Create(ctx, &ipAddressClaim); err != nil {
+			conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+			return nil, fmt.Errorf("create IPAddressClaim: %s", err)
+		}
+		r.Recorder.Eventf(machine, corev1.EventTypeNormal, "CreatedIPAddressClaim", "Created IPAddressClaim %q", ipAddressClaim.Name)
 	}
 
+	if ipAddressClaim.Status.AddressRef.Name == "" {
+		return nil, nil
+	}
+
+	var ipAddress capiipamv1alpha1.IPAddress
+	ipAddressKey := types.NamespacedName{
+		Namespace: ipAddressClaim.Namespace,
+		Name:      ipAddressClaim.Status.AddressRef.Name,
+	}
+	if err := r.Get(ctx, ipAddressKey, &ipAddress); err != nil {
+		conditions.MarkFalse(machine, infrastructurev1beta1.IPAddressAllocatedCondition, infrastructurev1beta1.IPClaimProvisioningFailedReason, capiv1beta1.ConditionSeverityWarning, err.Error())
+		return nil, fmt.Errorf("get IPAddress: %s", err)
+	}
+	return &interfaceAddress{
+		Address: ipAddress.Spec.Address,
+		Prefix:  ipAddress.Spec.Prefix,
+		Gateway: ipAddress.Spec.Gateway,
+	}, nil
+}
+
+// releaseIPAddress releases the IP address claimed for the binding. The finalizer of the IPClaim of the metal3 IPAM is
+// removed so that the IPClaim is garbage collected with the machine, while the IPAddressClaim of a Cluster API IPAM
+// provider is deleted.
+func (r *VirtinkMachineReconciler) releaseIPAddress(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine, binding ipPoolBinding) error {
+	ipClaimKey := types.NamespacedName{
+		Name:      binding.IPClaimName,
+		Namespace: machine.Namespace,
+	}
+
+	if ipPoolAPIGroup(binding.IPPoolRef) == capiipamv1alpha1.GroupVersion.Group {
+		var ipAddressClaim capiipamv1alpha1.IPAddressClaim
+		if err := r.Get(ctx, ipClaimKey, &ipAddressClaim); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("get IPAddressClaim: %s", err)
+		}
+		if ipAddressClaim.DeletionTimestamp.IsZero() {
+			if err := r.Delete(ctx, &ipAddressClaim); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("delete IPAddressClaim: %s", err)
+			}
+			r.Recorder.Eventf(machine, corev1.EventTypeNormal, "DeletedIPAddressClaim", "Deleted IPAddressClaim %q", ipAddressClaim.Name)
+		}
+		return nil
+	}
+
+	var ipClaim ipamv1.IPClaim
+	if err := r.Get(ctx, ipClaimKey, &ipClaim); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("get ipClaim: %s", err)
+	}
+	if controllerutil.ContainsFinalizer(&ipClaim, finalizer) {
+		controllerutil.RemoveFinalizer(&ipClaim, finalizer)
+		if err := r.Update(ctx, &ipClaim); err != nil {
+			return fmt.Errorf("update ipClaim: %s", err)
+		}
+		r.Recorder.Eventf(machine, corev1.EventTypeNormal, "ReleasedIPClaim", "Released IPClaim %q", ipClaim.Name)
+	}
 	return nil
 }
 
-func (r *VirtinkMachineReconciler) buildVM(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine, ownerMachine *capiv1beta1.Machine) (*virtv1alpha1.VirtualMachine, error) {
+// ipPoolAPIGroup returns the API group of the IP pool reference, defaulting to ipam.metal3.io for references created
+// before other API groups were supported.
+func ipPoolAPIGroup(ref corev1.TypedLocalObjectReference) string {
+	if ref.APIGroup == nil {
+		return ipamv1.GroupVersion.Group
+	}
+	return *ref.APIGroup
+}
+
+func markVolumesReady(machine *infrastructurev1beta1.VirtinkMachine, dataVolumes []cdiv1beta1.DataVolume) {
+	if len(machine.Spec.VolumeTemplates) == 0 {
+		conditions.Delete(machine, infrastructurev1beta1.VolumesReadyCondition)
+		return
+	}
+
+	for _, dataVolume := range dataVolumes {
+		switch dataVolume.Status.Phase {
+		case cdiv1beta1.Succeeded:
+		case cdiv1beta1.Failed:
+			conditions.MarkFalse(machine, infrastructurev1beta1.VolumesReadyCondition, infrastructurev1beta1.DataVolumeImportFailedReason, capiv1beta1.ConditionSeverityError, "DataVolume %q failed", dataVolume.Name)
+			return
+		default:
+			conditions.MarkFalse(machine, infrastructurev1beta1.VolumesReadyCondition, infrastructurev1beta1.WaitingForDataVolumeImportReason, capiv1beta1.ConditionSeverityInfo, "DataVolume %q is %s %s", dataVolume.Name, dataVolume.Status.Phase, dataVolume.Status.Progress)
+			return
+		}
+	}
+	conditions.MarkTrue(machine, infrastructurev1beta1.VolumesReadyCondition)
+}
+
+func (r *VirtinkMachineReconciler) buildMachineAddresses(ctx context.Context, infraClusterClient client.Client, machine *infrastructurev1beta1.VirtinkMachine, vm *virtv1alpha1.VirtualMachine, interfaceAddresses []interfaceAddress) ([]capiv1beta1.MachineAddress, error) {
+	addresses := []capiv1beta1.MachineAddress{{
+		Type:    capiv1beta1.MachineHostName,
+		Address: machine.Name,
+	}, {
+		Type:    capiv1beta1.MachineInternalDNS,
+		Address: machine.Name,
+	}}
+
+	seenIPs := map[string]bool{}
+	addInternalIP := func(ip string) {
+		if ip == "" || seenIPs[ip] {
+			return
+		}
+		seenIPs[ip] = true
+		addresses = append(addresses, capiv1beta1.MachineAddress{
+			Type:    capiv1beta1.MachineInternalIP,
+			Address: ip,
+		})
+	}
+
+	for _, interfaceAddress := range interfaceAddresses {
+		addInternalIP(interfaceAddress.Address)
+	}
+
+	if vm.Status.VMPodName != "" {
+		var vmPod corev1.Pod
+		vmPodKey := types.NamespacedName{
+			Name:      vm.Status.VMPodName,
+			Namespace: vm.Namespace,
+		}
+		if err := infraClusterClient.Get(ctx, vmPodKey, &vmPod); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("get VM Pod: %s", err)
+			}
+		} else {
+			addInternalIP(vmPod.Status.PodIP)
+			for _, podIP := range vmPod.Status.PodIPs {
+				addInternalIP(podIP.IP)
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+func (r *VirtinkMachineReconciler) buildVM(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster, machine *infrastructurev1beta1.VirtinkMachine, interfaceAddresses []interfaceAddress) (*virtv1alpha1.VirtualMachine, error) {
 	vm := &virtv1alpha1.VirtualMachine{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels:      machine.Labels,
+			Labels:      map[string]string{},
 			Annotations: machine.Annotations,
 		},
-		Spec: machine.Spec.VirtualMachineTemplate.Spec,
+		Spec: *machine.Spec.VirtualMachineTemplate.Spec.DeepCopy(),
+	}
+	for name, value := range machine.Labels {
+		vm.Labels[name] = value
+	}
+	setVirtinkMachineLabels(vm, machine)
+
+	// A failure domain with its own infra cluster is not a subset of the infra cluster nodes.
+	failureDomain := getFailureDomain(cluster, machine.Status.FailureDomain)
+	if machine.Status.FailureDomain != nil && *machine.Status.FailureDomain != "" && (failureDomain == nil || failureDomain.IdentityRef == nil) {
+		requireNodeSelectorRequirement(&vm.Spec, corev1.NodeSelectorRequirement{
+			Key:      failureDomainTopologyKey(cluster),
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{*machine.Status.FailureDomain},
+		})
 	}
 
 	for i := range vm.Spec.Volumes {
@@ -385,13 +1046,64 @@ func (r *VirtinkMachineReconciler) buildVM(ctx context.Context, machine *infrast
 		}
 	}
 
-	var secret corev1.Secret
-	secretKey := types.NamespacedName{
-		Namespace: machine.Namespace,
-		Name:      *ownerMachine.Spec.Bootstrap.DataSecretName,
+	cloudInit := &virtv1alpha1.CloudInitVolumeSource{
+		UserDataSecretName: bootstrapDataSecretName(machine),
+	}
+
+	// The interfaces of the VM keep the MAC addresses recorded for them, unless set in the VirtualMachineTemplate.
+	for i := range vm.Spec.Instance.Interfaces {
+		iface := &vm.Spec.Instance.Interfaces[i]
+		if iface.MAC == "" {
+			iface.MAC = recordedMACAddress(machine, iface.Name)
+		}
+	}
+
+	// The allocated IP addresses are configured on the interfaces of the VM, which are matched by their MAC addresses.
+	var networkDataAddresses []interfaceAddress
+	boundInterfaces := map[string]bool{}
+	for _, address := range interfaceAddresses {
+		boundInterfaces[address.InterfaceName] = true
+		var iface *virtv1alpha1.Interface
+		for i := range vm.Spec.Instance.Interfaces {
+			if vm.Spec.Instance.Interfaces[i].Name == address.InterfaceName {
+				iface = &vm.Spec.Instance.Interfaces[i]
+			}
+		}
+		if iface == nil {
+			continue
+		}
+		if iface.MAC == "" {
+			iface.MAC = address.MAC.String()
+		}
+		mac, err := net.ParseMAC(iface.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("parse MAC address of interface %q: %s", iface.Name, err)
+		}
+		address.MAC = mac
+		networkDataAddresses = append(networkDataAddresses, address)
 	}
-	if err := r.Get(ctx, secretKey, &secret); err != nil {
-		return nil, fmt.Errorf("get bootstrap Secret: %s", err)
+	if len(networkDataAddresses) > 0 {
+		var dhcpInterfaces []dhcpInterface
+		for i := range vm.Spec.Instance.Interfaces {
+			iface := &vm.Spec.Instance.Interfaces[i]
+			if boundInterfaces[iface.Name] || iface.MAC == "" {
+				continue
+			}
+			mac, err := net.ParseMAC(iface.MAC)
+			if err != nil {
+				return nil, fmt.Errorf("parse MAC address of interface %q: %s", iface.Name, err)
+			}
+			dhcpInterfaces = append(dhcpInterfaces, dhcpInterface{
+				InterfaceName: iface.Name,
+				MAC:           mac,
+			})
+		}
+
+		networkData, err := buildNetworkData(networkDataAddresses, dhcpInterfaces)
+		if err != nil {
+			return nil, fmt.Errorf("build network data: %s", err)
+		}
+		cloudInit.NetworkData = networkData
 	}
 
 	vm.Spec.Instance.Disks = append(vm.Spec.Instance.Disks, virtv1alpha1.Disk{
@@ -400,21 +1112,36 @@ func (r *VirtinkMachineReconciler) buildVM(ctx context.Context, machine *infrast
 	vm.Spec.Volumes = append(vm.Spec.Volumes, virtv1alpha1.Volume{
 		Name: "cloud-init",
 		VolumeSource: virtv1alpha1.VolumeSource{
-			CloudInit: &virtv1alpha1.CloudInitVolumeSource{
-				UserDataBase64: base64.StdEncoding.EncodeToString(secret.Data["value"]),
-			},
+			CloudInit: cloudInit,
 		},
 	})
 
 	return vm, nil
 }
 
-func (r *VirtinkMachineReconciler) buildDataVolumes(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine) []*cdiv1beta1.DataVolume {
-	infraNamespace := machine.Namespace
-	if machine.Spec.VirtualMachineTemplate.ObjectMeta.Namespace != "" {
-		infraNamespace = machine.Spec.VirtualMachineTemplate.ObjectMeta.Namespace
+// requireNodeSelectorRequirement adds the requirement to every required node selector term of the VM, so that the VM
+// can only be scheduled to the nodes matching the requirement.
+func requireNodeSelectorRequirement(vmSpec *virtv1alpha1.VirtualMachineSpec, requirement corev1.NodeSelectorRequirement) {
+	if vmSpec.Affinity == nil {
+		vmSpec.Affinity = &corev1.Affinity{}
+	}
+	if vmSpec.Affinity.NodeAffinity == nil {
+		vmSpec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := vmSpec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
 	}
+	nodeSelector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	for i := range nodeSelector.NodeSelectorTerms {
+		nodeSelector.NodeSelectorTerms[i].MatchExpressions = append(nodeSelector.NodeSelectorTerms[i].MatchExpressions, requirement)
+	}
+}
 
+func (r *VirtinkMachineReconciler) buildDataVolumes(ctx context.Context, machine *infrastructurev1beta1.VirtinkMachine, infraNamespace string) []*cdiv1beta1.DataVolume {
 	dataVolumes := []*cdiv1beta1.DataVolume{}
 	for _, volume := range machine.Spec.VolumeTemplates {
 		switch {
@@ -423,27 +1150,227 @@ func (r *VirtinkMachineReconciler) buildDataVolumes(ctx context.Context, machine
 				ObjectMeta: metav1.ObjectMeta{
 					Namespace: infraNamespace,
 					Name:      fmt.Sprintf("%s-%s", machine.Name, volume.DataVolume.Name),
+					Labels:    map[string]string{},
 				},
 				Spec: *volume.DataVolume.Spec.DeepCopy(),
 			}
+			for name, value := range volume.DataVolume.Labels {
+				dataVolume.Labels[name] = value
+			}
+			setVirtinkMachineLabels(&dataVolume, machine)
 			dataVolumes = append(dataVolumes, &dataVolume)
 		}
 	}
 	return dataVolumes
 }
 
-func generateMAC() (net.HardwareAddr, error) {
-	prefix := []byte{0x52, 0x54, 0x00}
-	suffix := make([]byte, 3)
-	if _, err := rand.Read(suffix); err != nil {
-		return nil, fmt.Errorf("rand: %s", err)
+func (r *VirtinkMachineReconciler) deleteDataVolumes(ctx context.Context, infraClusterClient client.Client, machine *infrastructurev1beta1.VirtinkMachine, infraNamespace string) (bool, error) {
+	allDeleted := true
+	for _, volume := range machine.Spec.VolumeTemplates {
+		if volume.DataVolume == nil || volume.ReclaimPolicy == infrastructurev1beta1.VolumeReclaimRetain {
+			continue
+		}
+
+		dataVolumeKey := types.NamespacedName{
+			Namespace: infraNamespace,
+			Name:      fmt.Sprintf("%s-%s", machine.Name, volume.DataVolume.Name),
+		}
+		var dataVolume cdiv1beta1.DataVolume
+		if err := infraClusterClient.Get(ctx, dataVolumeKey, &dataVolume); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return false, fmt.Errorf("get DataVolume: %s", err)
+			}
+		} else {
+			allDeleted = false
+			if dataVolume.DeletionTimestamp.IsZero() {
+				if err := infraClusterClient.Delete(ctx, &dataVolume); client.IgnoreNotFound(err) != nil {
+					return false, fmt.Errorf("delete DataVolume: %s", err)
+				}
+				r.Recorder.Eventf(machine, corev1.EventTypeNormal, "DeletedDataVolume", "Deleted DataVolume %q", dataVolume.Name)
+			}
+			continue
+		}
+
+		var pvc corev1.PersistentVolumeClaim
+		if err := infraClusterClient.Get(ctx, dataVolumeKey, &pvc); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return false, fmt.Errorf("get PVC: %s", err)
+			}
+		} else {
+			allDeleted = false
+			if pvc.DeletionTimestamp.IsZero() {
+				if err := infraClusterClient.Delete(ctx, &pvc); client.IgnoreNotFound(err) != nil {
+					return false, fmt.Errorf("delete PVC: %s", err)
+				}
+				r.Recorder.Eventf(machine, corev1.EventTypeNormal, "DeletedPVC", "Deleted PVC %q", pvc.Name)
+			}
+		}
+	}
+	return allDeleted, nil
+}
+
+// setVirtinkMachineLabels labels an infra object created for the machine, so that events of the object can be mapped
+// back to the machine.
+func setVirtinkMachineLabels(obj metav1.Object, machine *infrastructurev1beta1.VirtinkMachine) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
 	}
-	return net.HardwareAddr(append(prefix, suffix...)), nil
+	labels[virtinkMachineNameLabel] = machine.Name
+	labels[virtinkMachineNamespaceLabel] = machine.Namespace
+	obj.SetLabels(labels)
+}
+
+// ensureVirtinkMachineLabels labels an existing infra object of the machine which is not labeled yet, e.g. one created
+// by an earlier version of the controller, so that events of the object are mapped back to the machine.
+func ensureVirtinkMachineLabels(ctx context.Context, infraClusterClient client.Client, obj client.Object, machine *infrastructurev1beta1.VirtinkMachine) error {
+	if isCreatedForVirtinkMachine(obj, machine) {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	setVirtinkMachineLabels(obj, machine)
+	return infraClusterClient.Patch(ctx, obj, patch)
+}
+
+// isCreatedForVirtinkMachine returns whether the infra cluster object is labeled with the VirtinkMachine.
+func isCreatedForVirtinkMachine(obj client.Object, machine *infrastructurev1beta1.VirtinkMachine) bool {
+	labels := obj.GetLabels()
+	return labels[virtinkMachineNameLabel] == machine.Name && labels[virtinkMachineNamespaceLabel] == machine.Namespace
+}
+
+func infraObjectToVirtinkMachine(obj client.Object) []ctrl.Request {
+	labels := obj.GetLabels()
+	name, namespace := labels[virtinkMachineNameLabel], labels[virtinkMachineNamespaceLabel]
+	if name == "" || namespace == "" {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
+}
+
+// getInfraCluster returns the reference to the infra cluster kubeconfig Secret of the VirtinkMachine, or nil if the
+// management cluster itself is used for infra. The infra cluster and namespace are resolved from the failure domain of
+// the VirtinkMachine and recorded in its status once, so that its VM and DataVolumes are not leaked in the former infra
+// cluster when the failure domain is changed or removed. A VirtinkMachine which would now be placed in another infra
+// cluster or namespace is not reconciled anymore, but can still be deleted from the recorded one.
+func (r *VirtinkMachineReconciler) getInfraCluster(ctx context.Context, cluster *infrastructurev1beta1.VirtinkCluster, machine *infrastructurev1beta1.VirtinkMachine) (*corev1.ObjectReference, error) {
+	deleting := !machine.DeletionTimestamp.IsZero()
+	if deleting && machine.Status.InfraNamespace != "" {
+		return machine.Status.InfraClusterSecretRef, nil
+	}
+
+	failureDomain := getFailureDomain(cluster, machine.Status.FailureDomain)
+	infraClusterSecretRef, err := getFailureDomainInfraClusterSecretRef(ctx, r.Client, cluster, failureDomain, deleting)
+	if err != nil {
+		markInfraClusterUnreachable(machine, err)
+		return nil, fmt.Errorf("get infra cluster secret ref: %s", err)
+	}
+	infraNamespace := machine.Namespace
+	if machine.Spec.VirtualMachineTemplate.ObjectMeta.Namespace != "" {
+		infraNamespace = machine.Spec.VirtualMachineTemplate.ObjectMeta.Namespace
+	}
+	if failureDomain != nil && failureDomain.Namespace != "" {
+		infraNamespace = failureDomain.Namespace
+	}
+
+	if machine.Status.InfraNamespace == "" {
+		machine.Status.InfraClusterSecretRef = infraClusterSecretRef
+		machine.Status.InfraNamespace = infraNamespace
+		return infraClusterSecretRef, nil
+	}
+
+	if !isSameInfraClusterSecretRef(infraClusterSecretRef, machine.Status.InfraClusterSecretRef) || infraNamespace != machine.Status.InfraNamespace {
+		err := fmt.Errorf("infra cluster or namespace changed since the VM was created in namespace %q", machine.Status.InfraNamespace)
+		conditions.MarkFalse(machine, infrastructurev1beta1.InfraClusterReachableCondition, infrastructurev1beta1.InfraClusterChangedReason, capiv1beta1.ConditionSeverityError, err.Error())
+		return nil, err
+	}
+	return infraClusterSecretRef, nil
+}
+
+func isSameInfraClusterSecretRef(a *corev1.ObjectReference, b *corev1.ObjectReference) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+func (r *VirtinkMachineReconciler) watchInfraCluster(ctx context.Context, infraClusterSecretRef *corev1.ObjectReference, machine *infrastructurev1beta1.VirtinkMachine) error {
+	if err := r.Tracker.Watch(ctx, infraClusterSecretRef, InfraClusterWatchInput{
+		Name:         "virtinkmachine-virtualmachine",
+		Watcher:      r.controller,
+		Kind:         &virtv1alpha1.VirtualMachine{},
+		EventHandler: handler.EnqueueRequestsFromMapFunc(infraObjectToVirtinkMachine),
+	}); err != nil {
+		return err
+	}
+
+	// CDI is optional in the infra cluster, so DataVolumes are only watched once they are used.
+	if len(machine.Spec.VolumeTemplates) > 0 {
+		if err := r.Tracker.Watch(ctx, infraClusterSecretRef, InfraClusterWatchInput{
+			Name:         "virtinkmachine-datavolume",
+			Watcher:      r.controller,
+			Kind:         &cdiv1beta1.DataVolume{},
+			EventHandler: handler.EnqueueRequestsFromMapFunc(infraObjectToVirtinkMachine),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func (r *VirtinkMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&infrastructurev1beta1.VirtinkMachine{}).
-		Complete(r)
+func (r *VirtinkMachineReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	log := ctrl.LoggerFrom(ctx)
+	gvk, err := apiutil.GVKForObject(&infrastructurev1beta1.VirtinkMachine{}, mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	clusterToVirtinkMachines, err := capiutil.ClusterToObjectsMapper(mgr.GetClient(), &infrastructurev1beta1.VirtinkMachineList{}, mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&infrastructurev1beta1.VirtinkMachine{}, builder.WithPredicates(predicates.ResourceNotPausedAndHasFilterLabel(log, r.WatchFilterValue))).
+		Watches(
+			&source.Kind{Type: &capiv1beta1.Machine{}},
+			handler.EnqueueRequestsFromMapFunc(capiutil.MachineToInfrastructureMapFunc(gvk)),
+			builder.WithPredicates(predicates.ResourceHasFilterLabel(log, r.WatchFilterValue)),
+		).
+		Watches(
+			&source.Kind{Type: &capiv1beta1.Cluster{}},
+			handler.EnqueueRequestsFromMapFunc(clusterToVirtinkMachines),
+			builder.WithPredicates(predicates.ClusterUnpausedAndInfrastructureReady(log)),
+		)
+
+	// Virtink and CDI are optional in the management cluster when an external infra cluster is used.
+	for _, obj := range []client.Object{&virtv1alpha1.VirtualMachine{}, &cdiv1beta1.DataVolume{}} {
+		installed, err := isKindInstalled(mgr, obj)
+		if err != nil {
+			return err
+		}
+		if installed {
+			controllerBuilder = controllerBuilder.Watches(&source.Kind{Type: obj}, handler.EnqueueRequestsFromMapFunc(infraObjectToVirtinkMachine))
+		}
+	}
+
+	c, err := controllerBuilder.Build(r)
+	if err != nil {
+		return err
+	}
+	r.controller = c
+	return nil
+}
+
+func isKindInstalled(mgr ctrl.Manager, obj client.Object) (bool, error) {
+	gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
+	if err != nil {
+		return false, err
+	}
+	if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
@@ -1,18 +1,24 @@
 package controllers
 
 import (
-	"encoding/base64"
 	"fmt"
 
 	"github.com/google/uuid"
+	ipamv1 "github.com/metal3-io/ip-address-manager/api/v1alpha1"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	virtv1alpha1 "github.com/smartxworks/virtink/pkg/apis/virt/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	capiipamv1alpha1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -67,7 +73,8 @@ var _ = Describe("VirtinkMachine controller", func() {
 					Namespace: machineKey.Namespace,
 				},
 				Spec: capiv1beta1.MachineSpec{
-					ClusterName: clusterKey.Name,
+					ClusterName:   clusterKey.Name,
+					FailureDomain: pointer.String("zone-a"),
 				},
 			}
 			Expect(k8sClient.Create(ctx, &machine)).To(Succeed())
@@ -132,6 +139,15 @@ var _ = Describe("VirtinkMachine controller", func() {
 					return apierrors.IsNotFound(k8sClient.Get(ctx, virtualMachineKey, &vm))
 				}).Should(BeTrue())
 			})
+
+			It("should mark bootstrap data as waiting for cluster infrastructure", func() {
+				var virtinkMachine infrastructurev1beta1.VirtinkMachine
+				Eventually(func() string {
+					Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+					return conditions.GetReason(&virtinkMachine, infrastructurev1beta1.BootstrapDataReadyCondition)
+				}).Should(Equal(infrastructurev1beta1.WaitingForClusterInfrastructureReason))
+				Expect(conditions.IsFalse(&virtinkMachine, capiv1beta1.ReadyCondition)).To(BeTrue())
+			})
 		})
 
 		Context("when infrastructure cluster is ready", func() {
@@ -153,24 +169,430 @@ var _ = Describe("VirtinkMachine controller", func() {
 				})
 			})
 
-			Context("when bootstrap data secret is set", func() {
+			Context("when IP pool is a Cluster API IP pool", func() {
+				var ipAddressClaimKey types.NamespacedName
 				BeforeEach(func() {
-					var machine capiv1beta1.Machine
-					Expect(k8sClient.Get(ctx, machineKey, &machine)).To(Succeed())
-					secretName := machine.Name + "-" + "secret"
-					secret := corev1.Secret{
+					ipAddressClaimKey = virtinkMachineKey
+
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+					virtinkMachine.Spec.VirtualMachineTemplate.Spec.Instance.Interfaces = []virtv1alpha1.Interface{{Name: "pod"}, {Name: "storage"}}
+					virtinkMachine.Spec.IPPoolRef = &corev1.TypedLocalObjectReference{
+						APIGroup: pointer.String(capiipamv1alpha1.GroupVersion.Group),
+						Kind:     "InClusterIPPool",
+						Name:     "pool",
+					}
+					Expect(k8sClient.Update(ctx, &virtinkMachine)).To(Succeed())
+
+					setBootstrapDataSecret(machineKey, map[string]string{
+						"value": "#cloud-init",
+					})
+				})
+
+				It("should configure the address allocated for the IPAddressClaim", func() {
+					var ipAddressClaim capiipamv1alpha1.IPAddressClaim
+					Eventually(func() error {
+						return k8sClient.Get(ctx, ipAddressClaimKey, &ipAddressClaim)
+					}, "10s").Should(Succeed())
+					Expect(ipAddressClaim.Spec.PoolRef.Kind).To(Equal("InClusterIPPool"))
+
+					By("allocating the IP address")
+					ipAddress := capiipamv1alpha1.IPAddress{
 						ObjectMeta: metav1.ObjectMeta{
-							Name:      secretName,
-							Namespace: machine.Namespace,
+							Name:      ipAddressClaim.Name,
+							Namespace: ipAddressClaim.Namespace,
+						},
+						Spec: capiipamv1alpha1.IPAddressSpec{
+							ClaimRef: corev1.LocalObjectReference{Name: ipAddressClaim.Name},
+							PoolRef:  ipAddressClaim.Spec.PoolRef,
+							Address:  "10.0.0.10",
+							Prefix:   24,
+							Gateway:  "10.0.0.1",
+						},
+					}
+					Expect(k8sClient.Create(ctx, &ipAddress)).To(Succeed())
+					ipAddressClaim.Status.AddressRef = corev1.LocalObjectReference{Name: ipAddress.Name}
+					Expect(k8sClient.Status().Update(ctx, &ipAddressClaim)).To(Succeed())
+
+					var vm virtv1alpha1.VirtualMachine
+					Eventually(func() error {
+						return k8sClient.Get(ctx, virtualMachineKey, &vm)
+					}, "10s").Should(Succeed())
+					mac := vm.Spec.Instance.Interfaces[0].MAC
+					Expect(mac).NotTo(BeEmpty())
+					storageMAC := vm.Spec.Instance.Interfaces[1].MAC
+					Expect(storageMAC).NotTo(BeEmpty())
+					cloudInit := vm.Spec.Volumes[len(vm.Spec.Volumes)-1].CloudInit
+					Expect(cloudInit.NetworkData).To(MatchYAML(fmt.Sprintf(`
+version: 2
+ethernets:
+  pod:
+    match:
+      macaddress: %q
+    addresses:
+    - 10.0.0.10/24
+    routes:
+    - to: 0.0.0.0/0
+      via: 10.0.0.1
+  storage:
+    match:
+      macaddress: %q
+    dhcp4: true
+    dhcp6: true
+`, mac, storageMAC)))
+
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Eventually(func() []capiv1beta1.MachineAddress {
+						Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+						return virtinkMachine.Status.Addresses
+					}, "10s").Should(ContainElement(capiv1beta1.MachineAddress{Type: capiv1beta1.MachineInternalIP, Address: "10.0.0.10"}))
+					Expect(virtinkMachine.Status.MACAddresses).To(ConsistOf(infrastructurev1beta1.InterfaceMACAddress{
+						InterfaceName: "pod",
+						MACAddress:    mac,
+					}, infrastructurev1beta1.InterfaceMACAddress{
+						InterfaceName: "storage",
+						MACAddress:    storageMAC,
+					}))
+
+					By("deleting the VirtinkMachine")
+					Expect(k8sClient.Delete(ctx, &virtinkMachine)).To(Succeed())
+					Eventually(func() bool {
+						return apierrors.IsNotFound(k8sClient.Get(ctx, ipAddressClaimKey, &ipAddressClaim))
+					}, "10s").Should(BeTrue())
+				})
+			})
+
+			Context("when IP pool is referred without API group", func() {
+				BeforeEach(func() {
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+					virtinkMachine.Spec.IPPoolRef = &corev1.TypedLocalObjectReference{
+						Kind: "IPPool",
+						Name: "pool",
+					}
+					Expect(k8sClient.Update(ctx, &virtinkMachine)).To(Succeed())
+				})
+
+				It("should claim the IP address from the metal3 IPPool", func() {
+					var ipClaim ipamv1.IPClaim
+					Eventually(func() error {
+						return k8sClient.Get(ctx, virtinkMachineKey, &ipClaim)
+					}, "10s").Should(Succeed())
+					Expect(ipClaim.Spec.Pool.Name).To(Equal("pool"))
+
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Consistently(func() *capierrors.MachineStatusError {
+						Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+						return virtinkMachine.Status.FailureReason
+					}).Should(BeNil())
+				})
+			})
+
+			Context("when the VM was created before MAC addresses were recorded", func() {
+				BeforeEach(func() {
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+					virtinkMachine.Spec.VirtualMachineTemplate.Spec.Instance.Interfaces = []virtv1alpha1.Interface{{Name: "pod"}}
+					Expect(k8sClient.Update(ctx, &virtinkMachine)).To(Succeed())
+
+					vm := virtv1alpha1.VirtualMachine{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      virtualMachineKey.Name,
+							Namespace: virtualMachineKey.Namespace,
+						},
+						Spec: virtv1alpha1.VirtualMachineSpec{
+							Instance: virtv1alpha1.Instance{
+								CPU: virtv1alpha1.CPU{
+									Sockets:        uint32(1),
+									CoresPerSocket: uint32(2),
+								},
+								Interfaces: []virtv1alpha1.Interface{{
+									Name: "pod",
+									MAC:  "52:54:00:12:34:56",
+								}},
+							},
+						},
+					}
+					Expect(k8sClient.Create(ctx, &vm)).To(Succeed())
+
+					setBootstrapDataSecret(machineKey, map[string]string{
+						"value": "#cloud-init",
+					})
+				})
+
+				It("should record the MAC address of the VM", func() {
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Eventually(func() []infrastructurev1beta1.InterfaceMACAddress {
+						Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+						return virtinkMachine.Status.MACAddresses
+					}, "10s").Should(ConsistOf(infrastructurev1beta1.InterfaceMACAddress{
+						InterfaceName: "pod",
+						MACAddress:    "52:54:00:12:34:56",
+					}))
+				})
+			})
+
+			Context("when the interfaces are not bound to any IP pool", func() {
+				BeforeEach(func() {
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+					virtinkMachine.Spec.VirtualMachineTemplate.Spec.Instance.Interfaces = []virtv1alpha1.Interface{{Name: "pod"}, {Name: "storage"}}
+					Expect(k8sClient.Update(ctx, &virtinkMachine)).To(Succeed())
+
+					setBootstrapDataSecret(machineKey, map[string]string{
+						"value": "#cloud-init",
+					})
+				})
+
+				It("should set the recorded MAC addresses on the interfaces of the VM", func() {
+					var vm virtv1alpha1.VirtualMachine
+					Eventually(func() error {
+						return k8sClient.Get(ctx, virtualMachineKey, &vm)
+					}, "10s").Should(Succeed())
+					Expect(vm.Spec.Volumes[0].CloudInit.NetworkData).To(BeEmpty())
+
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+					Expect(virtinkMachine.Status.MACAddresses).To(HaveLen(2))
+					Expect(conditions.Has(&virtinkMachine, infrastructurev1beta1.IPAddressAllocatedCondition)).To(BeFalse())
+					for _, iface := range vm.Spec.Instance.Interfaces {
+						Expect(iface.MAC).NotTo(BeEmpty())
+						Expect(virtinkMachine.Status.MACAddresses).To(ContainElement(infrastructurev1beta1.InterfaceMACAddress{
+							InterfaceName: iface.Name,
+							MACAddress:    iface.MAC,
+						}))
+					}
+				})
+			})
+
+			// Ignition is not supported, since it reads its config neither from the user data of the NoCloud disk nor
+			// from any other source a Virtink VM provides.
+			for format, data := range map[string]string{
+				"shell-script": "#!/bin/bash",
+				"ignition":     `{"ignition":{"version":"3.3.0"}}`,
+			} {
+				format, data := format, data
+				Context("when bootstrap data is in "+format+" format", func() {
+					BeforeEach(func() {
+						setBootstrapDataSecret(machineKey, map[string]string{
+							"value":  data,
+							"format": format,
+						})
+					})
+
+					It("should fail the machine", func() {
+						var virtinkMachine infrastructurev1beta1.VirtinkMachine
+						Eventually(func() *capierrors.MachineStatusError {
+							Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+							return virtinkMachine.Status.FailureReason
+						}, "10s").ShouldNot(BeNil())
+						Expect(*virtinkMachine.Status.FailureReason).To(Equal(capierrors.InvalidConfigurationMachineError))
+						Expect(*virtinkMachine.Status.FailureMessage).To(ContainSubstring(format))
+						Expect(conditions.GetReason(&virtinkMachine, infrastructurev1beta1.BootstrapDataReadyCondition)).To(Equal(infrastructurev1beta1.UnsupportedBootstrapDataFormatReason))
+
+						var vm virtv1alpha1.VirtualMachine
+						Consistently(func() bool {
+							return apierrors.IsNotFound(k8sClient.Get(ctx, virtualMachineKey, &vm))
+						}).Should(BeTrue())
+					})
+				})
+			}
+
+			Context("when volume templates are set", func() {
+				var rootfsDataVolumeKey types.NamespacedName
+				var dataDataVolumeKey types.NamespacedName
+				BeforeEach(func() {
+					rootfsDataVolumeKey = types.NamespacedName{Namespace: virtualMachineKey.Namespace, Name: virtinkMachineKey.Name + "-rootfs"}
+					dataDataVolumeKey = types.NamespacedName{Namespace: virtualMachineKey.Namespace, Name: virtinkMachineKey.Name + "-data"}
+
+					dataVolumeSpec := cdiv1beta1.DataVolumeSpec{
+						Source: &cdiv1beta1.DataVolumeSource{
+							Blank: &cdiv1beta1.DataVolumeBlankImage{},
+						},
+						PVC: &corev1.PersistentVolumeClaimSpec{
+							AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+							},
+						},
+					}
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Eventually(func() error {
+						Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+						virtinkMachine.Spec.VolumeTemplates = []infrastructurev1beta1.VolumeTemplateSource{{
+							DataVolume: &infrastructurev1beta1.VolumeTemplateSourceDataVolume{
+								ObjectMeta: metav1.ObjectMeta{Name: "rootfs"},
+								Spec:       dataVolumeSpec,
+							},
+						}, {
+							DataVolume: &infrastructurev1beta1.VolumeTemplateSourceDataVolume{
+								ObjectMeta: metav1.ObjectMeta{Name: "data"},
+								Spec:       dataVolumeSpec,
+							},
+							ReclaimPolicy: infrastructurev1beta1.VolumeReclaimRetain,
+						}}
+						return k8sClient.Update(ctx, &virtinkMachine)
+					}).Should(Succeed())
+				})
+
+				JustBeforeEach(func() {
+					setBootstrapDataSecret(machineKey, map[string]string{
+						"value": "#cloud-init",
+					})
+				})
+
+				It("should delete DataVolumes unless they are retained", func() {
+					var dataVolume cdiv1beta1.DataVolume
+					Eventually(func() error {
+						return k8sClient.Get(ctx, rootfsDataVolumeKey, &dataVolume)
+					}, "10s").Should(Succeed())
+					Eventually(func() error {
+						return k8sClient.Get(ctx, dataDataVolumeKey, &dataVolume)
+					}, "10s").Should(Succeed())
+					Eventually(func() error {
+						return k8sClient.Get(ctx, virtualMachineKey, &virtv1alpha1.VirtualMachine{})
+					}, "10s").Should(Succeed())
+
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+					Expect(k8sClient.Delete(ctx, &virtinkMachine)).To(Succeed())
+					Eventually(func() bool {
+						return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine))
+					}, "20s").Should(BeTrue())
+
+					Expect(apierrors.IsNotFound(k8sClient.Get(ctx, rootfsDataVolumeKey, &dataVolume))).To(BeTrue())
+					Expect(k8sClient.Get(ctx, dataDataVolumeKey, &dataVolume)).To(Succeed())
+					Expect(dataVolume.DeletionTimestamp.IsZero()).To(BeTrue())
+				})
+
+				Context("when the PVC of a DataVolume is left behind", func() {
+					BeforeEach(func() {
+						pvc := corev1.PersistentVolumeClaim{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      rootfsDataVolumeKey.Name,
+								Namespace: rootfsDataVolumeKey.Namespace,
+							},
+							Spec: corev1.PersistentVolumeClaimSpec{
+								AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+								},
+							},
+						}
+						Expect(k8sClient.Create(ctx, &pvc)).To(Succeed())
+					})
+
+					It("should delete the PVC", func() {
+						Eventually(func() error {
+							return k8sClient.Get(ctx, virtualMachineKey, &virtv1alpha1.VirtualMachine{})
+						}, "10s").Should(Succeed())
+						Expect(apierrors.IsNotFound(k8sClient.Get(ctx, rootfsDataVolumeKey, &cdiv1beta1.DataVolume{}))).To(BeTrue())
+
+						var virtinkMachine infrastructurev1beta1.VirtinkMachine
+						Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+						Expect(k8sClient.Delete(ctx, &virtinkMachine)).To(Succeed())
+
+						// The PVC protection finalizer is not removed without kube-controller-manager.
+						var pvc corev1.PersistentVolumeClaim
+						Eventually(func() bool {
+							if err := k8sClient.Get(ctx, rootfsDataVolumeKey, &pvc); err != nil {
+								return apierrors.IsNotFound(err)
+							}
+							return !pvc.DeletionTimestamp.IsZero()
+						}, "10s").Should(BeTrue())
+						Eventually(func() error {
+							if err := k8sClient.Get(ctx, rootfsDataVolumeKey, &pvc); err != nil {
+								return client.IgnoreNotFound(err)
+							}
+							pvc.Finalizers = nil
+							return k8sClient.Update(ctx, &pvc)
+						}).Should(Succeed())
+
+						Eventually(func() bool {
+							return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine))
+						}, "20s").Should(BeTrue())
+					})
+				})
+			})
+
+			Context("when the VM was created without labels", func() {
+				BeforeEach(func() {
+					vm := virtv1alpha1.VirtualMachine{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      virtualMachineKey.Name,
+							Namespace: virtualMachineKey.Namespace,
+						},
+						Spec: virtv1alpha1.VirtualMachineSpec{
+							Instance: virtv1alpha1.Instance{
+								CPU: virtv1alpha1.CPU{
+									Sockets:        uint32(1),
+									CoresPerSocket: uint32(2),
+								},
+							},
+						},
+					}
+					Expect(k8sClient.Create(ctx, &vm)).To(Succeed())
+
+					setBootstrapDataSecret(machineKey, map[string]string{
+						"value": "#cloud-init",
+					})
+				})
+
+				It("should label the VM", func() {
+					var vm virtv1alpha1.VirtualMachine
+					Eventually(func() map[string]string {
+						Expect(k8sClient.Get(ctx, virtualMachineKey, &vm)).To(Succeed())
+						return vm.Labels
+					}, "10s").Should(And(
+						HaveKeyWithValue(virtinkMachineNameLabel, virtinkMachineKey.Name),
+						HaveKeyWithValue(virtinkMachineNamespaceLabel, virtinkMachineKey.Namespace),
+					))
+				})
+			})
+
+			Context("when a Secret of the bootstrap data Secret name already exists", func() {
+				var bootstrapDataSecretKey types.NamespacedName
+				BeforeEach(func() {
+					bootstrapDataSecretKey = types.NamespacedName{
+						Name:      virtinkMachineKey.Name + "-userdata",
+						Namespace: virtualMachineKey.Namespace,
+					}
+					existingSecret := corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      bootstrapDataSecretKey.Name,
+							Namespace: bootstrapDataSecretKey.Namespace,
 						},
 						StringData: map[string]string{
-							"value": "#cloud-init",
+							"value": "#existing",
 						},
 					}
-					Expect(k8sClient.Create(ctx, &secret)).To(Succeed())
+					Expect(k8sClient.Create(ctx, &existingSecret)).To(Succeed())
 
-					machine.Spec.Bootstrap.DataSecretName = &secretName
-					Expect(k8sClient.Update(ctx, &machine)).To(Succeed())
+					setBootstrapDataSecret(machineKey, map[string]string{
+						"value": "#cloud-init",
+					})
+				})
+
+				It("should not overwrite the Secret", func() {
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Eventually(func() string {
+						Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+						return conditions.GetReason(&virtinkMachine, infrastructurev1beta1.BootstrapDataReadyCondition)
+					}, "10s").Should(Equal(infrastructurev1beta1.BootstrapDataSecretSyncFailedReason))
+
+					var secret corev1.Secret
+					Expect(k8sClient.Get(ctx, bootstrapDataSecretKey, &secret)).To(Succeed())
+					Expect(secret.Data).To(HaveKeyWithValue("value", []byte("#existing")))
+					Expect(secret.Labels).NotTo(HaveKey(virtinkMachineNameLabel))
+					Expect(apierrors.IsNotFound(k8sClient.Get(ctx, virtualMachineKey, &virtv1alpha1.VirtualMachine{}))).To(BeTrue())
+				})
+			})
+
+			Context("when bootstrap data secret is set", func() {
+				BeforeEach(func() {
+					setBootstrapDataSecret(machineKey, map[string]string{
+						"value": "#cloud-init",
+					})
 				})
 
 				It("should create a virtink VM", func() {
@@ -178,10 +600,28 @@ var _ = Describe("VirtinkMachine controller", func() {
 					Eventually(func() error {
 						return k8sClient.Get(ctx, virtualMachineKey, &vm)
 					}, "10s").Should(Succeed())
-					Expect(vm.Spec.Volumes[0].CloudInit.UserDataBase64).To(Equal(base64.StdEncoding.EncodeToString([]byte("#cloud-init"))))
+					Expect(vm.Spec.Volumes[0].CloudInit.UserDataSecretName).To(Equal(virtinkMachineKey.Name + "-userdata"))
 					Expect(vm.Namespace).To(Equal("infra-namespace"))
 
+					var bootstrapDataSecret corev1.Secret
+					Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: vm.Namespace, Name: vm.Spec.Volumes[0].CloudInit.UserDataSecretName}, &bootstrapDataSecret)).To(Succeed())
+					Expect(bootstrapDataSecret.Data).To(HaveKeyWithValue("value", []byte("#cloud-init")))
+					Expect(bootstrapDataSecret.Labels).To(HaveKeyWithValue(virtinkMachineNameLabel, virtinkMachineKey.Name))
+					Expect(vm.Labels).To(HaveKeyWithValue(virtinkMachineNameLabel, virtinkMachineKey.Name))
+					Expect(vm.Labels).To(HaveKeyWithValue(virtinkMachineNamespaceLabel, virtinkMachineKey.Namespace))
+					Expect(vm.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).To(ConsistOf(
+						HaveField("MatchExpressions", ConsistOf(corev1.NodeSelectorRequirement{
+							Key:      corev1.LabelTopologyZone,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"zone-a"},
+						})),
+					))
+
 					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+					Expect(virtinkMachine.Status.FailureDomain).To(Equal(pointer.String("zone-a")))
+					Expect(virtinkMachine.Status.InfraNamespace).To(Equal("infra-namespace"))
+					Expect(virtinkMachine.Status.InfraClusterSecretRef).To(BeNil())
 					Eventually(func() bool {
 						Expect(k8sClient.Get(ctx, machineKey, &virtinkMachine)).To(Succeed())
 						return *virtinkMachine.Spec.ProviderID == fmt.Sprintf("virtink://%s", vm.UID) &&
@@ -189,6 +629,17 @@ var _ = Describe("VirtinkMachine controller", func() {
 					})
 				})
 
+				It("should set machine addresses", func() {
+					var virtinkMachine infrastructurev1beta1.VirtinkMachine
+					Eventually(func() []capiv1beta1.MachineAddress {
+						Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+						return virtinkMachine.Status.Addresses
+					}, "20s").Should(ContainElements(
+						capiv1beta1.MachineAddress{Type: capiv1beta1.MachineHostName, Address: virtinkMachineKey.Name},
+						capiv1beta1.MachineAddress{Type: capiv1beta1.MachineInternalDNS, Address: virtinkMachineKey.Name},
+					))
+				})
+
 				Context("when deleting VirtinkMachine", func() {
 					It("should delete virtink VM and remove finalizer", func() {
 						var vm virtv1alpha1.VirtualMachine
@@ -207,6 +658,79 @@ var _ = Describe("VirtinkMachine controller", func() {
 						Eventually(func() bool {
 							return apierrors.IsNotFound(k8sClient.Get(ctx, virtualMachineKey, &vm))
 						}).Should(BeTrue())
+						Eventually(func() bool {
+							return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine))
+						}, "10s").Should(BeTrue())
+						bootstrapDataSecretKey := types.NamespacedName{Namespace: virtualMachineKey.Namespace, Name: virtualMachineKey.Name + "-userdata"}
+						Expect(apierrors.IsNotFound(k8sClient.Get(ctx, bootstrapDataSecretKey, &corev1.Secret{}))).To(BeTrue())
+					})
+
+					It("should delete virtink VM from the recorded namespace after the failure domain is changed", func() {
+						var vm virtv1alpha1.VirtualMachine
+						Eventually(func() error {
+							return k8sClient.Get(ctx, virtualMachineKey, &vm)
+						}, "10s").Should(Succeed())
+
+						var virtinkCluster infrastructurev1beta1.VirtinkCluster
+						Expect(k8sClient.Get(ctx, clusterKey, &virtinkCluster)).To(Succeed())
+						virtinkCluster.Spec.FailureDomains = []infrastructurev1beta1.FailureDomain{{
+							Name:      "zone-a",
+							Namespace: "default",
+						}}
+						Expect(k8sClient.Update(ctx, &virtinkCluster)).To(Succeed())
+
+						By("touching the VirtinkMachine to reconcile it again")
+						var virtinkMachine infrastructurev1beta1.VirtinkMachine
+						Eventually(func() error {
+							Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+							virtinkMachine.Annotations = map[string]string{"test.virtink.smartx.com/touched": "true"}
+							return k8sClient.Update(ctx, &virtinkMachine)
+						}).Should(Succeed())
+						Eventually(func() string {
+							Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+							return conditions.GetReason(&virtinkMachine, infrastructurev1beta1.InfraClusterReachableCondition)
+						}, "10s").Should(Equal(infrastructurev1beta1.InfraClusterChangedReason))
+
+						Expect(k8sClient.Delete(ctx, &virtinkMachine)).To(Succeed())
+						Eventually(func() bool {
+							return apierrors.IsNotFound(k8sClient.Get(ctx, virtualMachineKey, &vm))
+						}).Should(BeTrue())
+						Eventually(func() bool {
+							return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine))
+						}, "10s").Should(BeTrue())
+					})
+
+					It("should keep finalizer until virtink VM is gone", func() {
+						var vm virtv1alpha1.VirtualMachine
+						Eventually(func() error {
+							return k8sClient.Get(ctx, virtualMachineKey, &vm)
+						}, "10s").Should(Succeed())
+						controllerutil.AddFinalizer(&vm, "test.virtink.smartx.com")
+						Expect(k8sClient.Update(ctx, &vm)).To(Succeed())
+
+						virtinkMachine := infrastructurev1beta1.VirtinkMachine{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      virtinkMachineKey.Name,
+								Namespace: virtinkMachineKey.Namespace,
+							},
+						}
+						Expect(k8sClient.Delete(ctx, &virtinkMachine)).To(Succeed())
+
+						Eventually(func() bool {
+							Expect(k8sClient.Get(ctx, virtualMachineKey, &vm)).To(Succeed())
+							return !vm.DeletionTimestamp.IsZero()
+						}).Should(BeTrue())
+						Consistently(func() bool {
+							Expect(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine)).To(Succeed())
+							return controllerutil.ContainsFinalizer(&virtinkMachine, finalizer)
+						}).Should(BeTrue())
+						Expect(conditions.GetReason(&virtinkMachine, infrastructurev1beta1.VMProvisionedCondition)).To(Equal(capiv1beta1.DeletingReason))
+
+						controllerutil.RemoveFinalizer(&vm, "test.virtink.smartx.com")
+						Expect(k8sClient.Update(ctx, &vm)).To(Succeed())
+						Eventually(func() bool {
+							return apierrors.IsNotFound(k8sClient.Get(ctx, virtinkMachineKey, &virtinkMachine))
+						}, "10s").Should(BeTrue())
 					})
 				})
 			})
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	capipatch "sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrastructurev1beta1 "github.com/smartxworks/cluster-api-provider-virtink/api/v1beta1"
+)
+
+// defaultMaxPods is the default maximum number of pods the kubelet of a node can run.
+const defaultMaxPods = 110
+
+// VirtinkMachineTemplateReconciler reconciles a VirtinkMachineTemplate object
+type VirtinkMachineTemplateReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=virtinkmachinetemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=virtinkmachinetemplates/status,verbs=get;update;patch
+
+// Reconcile keeps the capacity and node info of the nodes created from the VirtinkMachineTemplate up to date in its
+// status, which are used by cluster-autoscaler to scale MachineDeployments up from zero.
+func (r *VirtinkMachineTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, rerr error) {
+	var template infrastructurev1beta1.VirtinkMachineTemplate
+	if err := r.Get(ctx, req.NamespacedName, &template); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if annotations.HasPaused(&template) {
+		ctrl.LoggerFrom(ctx).Info("reconciliation is paused for this object")
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := capipatch.NewHelper(&template, r.Client)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("create MachineTemplate patch helper: %s", err)
+	}
+
+	template.Status.Capacity = buildMachineCapacity(&template.Spec.Template.Spec)
+	template.Status.NodeInfo = buildMachineNodeInfo(&template.Spec.Template.Spec)
+
+	if err := patchHelper.Patch(ctx, &template); err != nil {
+		return ctrl.Result{}, fmt.Errorf("patch MachineTemplate: %s", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// buildMachineCapacity returns the resource capacity of the node running in the VM of the machine. The
+// ephemeral-storage is the size of the rootfs volume, which is the volume of the first disk of the VM.
+func buildMachineCapacity(spec *infrastructurev1beta1.VirtinkMachineSpec) corev1.ResourceList {
+	instance := spec.VirtualMachineTemplate.Spec.Instance
+	sockets, coresPerSocket := instance.CPU.Sockets, instance.CPU.CoresPerSocket
+	if sockets == 0 {
+		sockets = 1
+	}
+	if coresPerSocket == 0 {
+		coresPerSocket = 1
+	}
+
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:  *resource.NewQuantity(int64(sockets*coresPerSocket), resource.DecimalSI),
+		corev1.ResourcePods: *resource.NewQuantity(defaultMaxPods, resource.DecimalSI),
+	}
+	if instance.Memory.Size != nil {
+		capacity[corev1.ResourceMemory] = instance.Memory.Size.DeepCopy()
+	}
+	if size := rootfsSize(spec); size != nil {
+		capacity[corev1.ResourceEphemeralStorage] = *size
+	}
+	return capacity
+}
+
+func rootfsSize(spec *infrastructurev1beta1.VirtinkMachineSpec) *resource.Quantity {
+	vmSpec := spec.VirtualMachineTemplate.Spec
+	if len(vmSpec.Instance.Disks) == 0 {
+		return nil
+	}
+
+	for _, volume := range vmSpec.Volumes {
+		if volume.Name != vmSpec.Instance.Disks[0].Name {
+			continue
+		}
+		switch {
+		case volume.ContainerRootfs != nil:
+			size := volume.ContainerRootfs.Size.DeepCopy()
+			return &size
+		case volume.DataVolume != nil:
+			for _, volumeTemplate := range spec.VolumeTemplates {
+				if volumeTemplate.DataVolume == nil || volumeTemplate.DataVolume.Name != volume.DataVolume.VolumeName {
+					continue
+				}
+				dataVolumeSpec := volumeTemplate.DataVolume.Spec
+				var requests corev1.ResourceList
+				switch {
+				case dataVolumeSpec.PVC != nil:
+					requests = dataVolumeSpec.PVC.Resources.Requests
+				case dataVolumeSpec.Storage != nil:
+					requests = dataVolumeSpec.Storage.Resources.Requests
+				}
+				if size, ok := requests[corev1.ResourceStorage]; ok {
+					return &size
+				}
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// buildMachineNodeInfo returns the node info of the node running in the VM of the machine. The architecture is only
+// known if the VM is required to be scheduled to infra cluster nodes of a single architecture.
+func buildMachineNodeInfo(spec *infrastructurev1beta1.VirtinkMachineSpec) *infrastructurev1beta1.NodeInfo {
+	nodeInfo := &infrastructurev1beta1.NodeInfo{
+		OperatingSystem: "linux",
+	}
+
+	vmSpec := spec.VirtualMachineTemplate.Spec
+	if arch, ok := vmSpec.NodeSelector[corev1.LabelArchStable]; ok {
+		nodeInfo.Architecture = arch
+		return nodeInfo
+	}
+
+	if vmSpec.Affinity != nil && vmSpec.Affinity.NodeAffinity != nil && vmSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		terms := vmSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		if len(terms) == 1 {
+			for _, req := range terms[0].MatchExpressions {
+				if req.Key == corev1.LabelArchStable && req.Operator == corev1.NodeSelectorOpIn && len(req.Values) == 1 {
+					nodeInfo.Architecture = req.Values[0]
+				}
+			}
+		}
+	}
+	return nodeInfo
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *VirtinkMachineTemplateReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&infrastructurev1beta1.VirtinkMachineTemplate{}, builder.WithPredicates(predicates.ResourceNotPausedAndHasFilterLabel(ctrl.LoggerFrom(ctx), r.WatchFilterValue))).
+		Complete(r)
+}
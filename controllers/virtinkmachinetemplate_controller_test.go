@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	virtv1alpha1 "github.com/smartxworks/virtink/pkg/apis/virt/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	infrastructurev1beta1 "github.com/smartxworks/cluster-api-provider-virtink/api/v1beta1"
+)
+
+var _ = Describe("VirtinkMachineTemplate controller", func() {
+	Context("for a VirtinkMachineTemplate", func() {
+		var templateKey types.NamespacedName
+		BeforeEach(func() {
+			By("creating a new VirtinkMachineTemplate")
+			templateKey = types.NamespacedName{
+				Name:      "template-" + uuid.New().String(),
+				Namespace: "default",
+			}
+
+			memorySize := resource.MustParse("4Gi")
+			template := infrastructurev1beta1.VirtinkMachineTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      templateKey.Name,
+					Namespace: templateKey.Namespace,
+				},
+				Spec: infrastructurev1beta1.VirtinkMachineTemplateSpec{
+					Template: infrastructurev1beta1.VirtinkMachineTemplateSpecTemplate{
+						Spec: infrastructurev1beta1.VirtinkMachineSpec{
+							VirtualMachineTemplate: infrastructurev1beta1.VirtualMachineTemplateSpec{
+								Spec: virtv1alpha1.VirtualMachineSpec{
+									NodeSelector: map[string]string{corev1.LabelArchStable: "arm64"},
+									Instance: virtv1alpha1.Instance{
+										CPU:    virtv1alpha1.CPU{Sockets: 1, CoresPerSocket: 2},
+										Memory: virtv1alpha1.Memory{Size: &memorySize},
+										Disks:  []virtv1alpha1.Disk{{Name: "rootfs"}},
+									},
+									Volumes: []virtv1alpha1.Volume{{
+										Name: "rootfs",
+										VolumeSource: virtv1alpha1.VolumeSource{
+											ContainerRootfs: &virtv1alpha1.ContainerRootfsVolumeSource{
+												Image: "smartxworks/capch-rootfs-1.24.0",
+												Size:  resource.MustParse("8Gi"),
+											},
+										},
+									}},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, &template)).To(Succeed())
+		})
+
+		It("should set capacity and node info", func() {
+			var template infrastructurev1beta1.VirtinkMachineTemplate
+			Eventually(func() corev1.ResourceList {
+				Expect(k8sClient.Get(ctx, templateKey, &template)).To(Succeed())
+				return template.Status.Capacity
+			}).ShouldNot(BeEmpty())
+			Expect(template.Status.Capacity.Cpu().Value()).To(Equal(int64(2)))
+			Expect(template.Status.Capacity.Memory().String()).To(Equal("4Gi"))
+			Expect(template.Status.Capacity.StorageEphemeral().String()).To(Equal("8Gi"))
+			Expect(template.Status.Capacity.Pods().Value()).To(Equal(int64(defaultMaxPods)))
+			Expect(template.Status.NodeInfo).To(Equal(&infrastructurev1beta1.NodeInfo{
+				Architecture:    "arm64",
+				OperatingSystem: "linux",
+			}))
+		})
+	})
+})
@@ -2,7 +2,9 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -15,6 +17,7 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	cdiv1beta1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capiipamv1alpha1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -35,6 +38,7 @@ func init() {
 	utilruntime.Must(virtv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(cdiv1beta1.AddToScheme(scheme))
 	utilruntime.Must(ipamv1.AddToScheme(scheme))
+	utilruntime.Must(capiipamv1alpha1.AddToScheme(scheme))
 
 	utilruntime.Must(infrastructurev1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
@@ -44,11 +48,34 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var machineDeletionTimeout time.Duration
+	var watchFilterValue string
+	var allowInfraClusterExecPlugins bool
+	var allowInfraClusterCredentialFiles bool
+	var macAddressPrefix string
+	var deterministicMACAddresses bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&machineDeletionTimeout, "machine-deletion-timeout", 10*time.Minute,
+		"The duration after which a warning event is raised for a VirtinkMachine whose VM or volumes are still being deleted. "+
+			"Zero disables the warning.")
+	flag.StringVar(&watchFilterValue, "watch-filter", "",
+		fmt.Sprintf("Label value that the controller watches to reconcile cluster-api objects. Label key is always %s. "+
+			"If unspecified, the controller watches for all cluster-api objects.", capiv1beta1.WatchLabel))
+	flag.BoolVar(&allowInfraClusterExecPlugins, "allow-infra-cluster-exec-plugins", false,
+		"Allow exec credential plugins and auth providers in infra cluster kubeconfigs, which run commands or plugins inside the controller. "+
+			"Only enable this if all the infra cluster kubeconfig Secrets are trusted.")
+	flag.BoolVar(&allowInfraClusterCredentialFiles, "allow-infra-cluster-credential-files", false,
+		"Allow certificate, key and token file paths in infra cluster kubeconfigs, which are read from the file system of the controller. "+
+			"Only enable this if all the infra cluster kubeconfig Secrets are trusted.")
+	flag.StringVar(&macAddressPrefix, "mac-address-prefix", "52:54:00",
+		"The prefix of the MAC addresses allocated to the interfaces of VMs, e.g. an OUI of 1 to 5 bytes.")
+	flag.BoolVar(&deterministicMACAddresses, "deterministic-mac-addresses", false,
+		"Derive the MAC addresses of VM interfaces from the hash of the namespace, cluster, machine and interface names "+
+			"instead of generating them randomly.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -57,6 +84,12 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	parsedMACAddressPrefix, err := controllers.ParseMACAddressPrefix(macAddressPrefix)
+	if err != nil {
+		setupLog.Error(err, "invalid MAC address prefix")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -70,23 +103,58 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
 	recorder := mgr.GetEventRecorderFor("capch-controller-manager")
+	tracker := controllers.NewInfraClusterTracker(mgr.GetClient(), mgr.GetScheme(), allowInfraClusterExecPlugins, allowInfraClusterCredentialFiles)
 	if err = (&controllers.VirtinkClusterReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: recorder,
-	}).SetupWithManager(mgr); err != nil {
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		Recorder:         recorder,
+		Tracker:          tracker,
+		WatchFilterValue: watchFilterValue,
+	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VirtinkCluster")
 		os.Exit(1)
 	}
 	if err = (&controllers.VirtinkMachineReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: recorder,
-	}).SetupWithManager(mgr); err != nil {
+		Client:                    mgr.GetClient(),
+		Scheme:                    mgr.GetScheme(),
+		Recorder:                  recorder,
+		Tracker:                   tracker,
+		DeletionTimeout:           machineDeletionTimeout,
+		WatchFilterValue:          watchFilterValue,
+		MACAddressPrefix:          parsedMACAddressPrefix,
+		DeterministicMACAddresses: deterministicMACAddresses,
+	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VirtinkMachine")
 		os.Exit(1)
 	}
+	if err = (&controllers.VirtinkMachineTemplateReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		WatchFilterValue: watchFilterValue,
+	}).SetupWithManager(ctx, mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "VirtinkMachineTemplate")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&infrastructurev1beta1.VirtinkCluster{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "VirtinkCluster")
+			os.Exit(1)
+		}
+		if err = (&infrastructurev1beta1.VirtinkClusterTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "VirtinkClusterTemplate")
+			os.Exit(1)
+		}
+		if err = (&infrastructurev1beta1.VirtinkMachine{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "VirtinkMachine")
+			os.Exit(1)
+		}
+		if err = (&infrastructurev1beta1.VirtinkMachineTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "VirtinkMachineTemplate")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -99,7 +167,7 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}